@@ -3,6 +3,8 @@ module github.com/sjc5/_lotus
 go 1.23.0
 
 require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/HugoSmits86/nativewebp v1.2.1
 	github.com/adrg/frontmatter v0.2.0
 	github.com/evanw/esbuild v0.24.2
 	github.com/go-chi/chi/v5 v5.2.0
@@ -11,11 +13,12 @@ require (
 	github.com/sjc5/hwy v0.16.3
 	github.com/sjc5/kiruna v0.0.64
 	github.com/sjc5/kit v0.0.76
-	golang.org/x/sync v0.10.0
+	golang.org/x/image v0.24.0
+	golang.org/x/sync v0.11.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
-	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/bmatcuk/doublestar/v4 v4.8.1 // indirect
 	github.com/fsnotify/fsnotify v1.8.0 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.8 // indirect
@@ -32,6 +35,5 @@ require (
 	golang.org/x/net v0.34.0 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/term v0.28.0 // indirect
-	golang.org/x/text v0.21.0 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 )
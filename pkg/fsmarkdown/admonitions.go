@@ -0,0 +1,185 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+)
+
+// AdmonitionOptions configures the HTML produced for callout blocks, which
+// are written either GitHub-style:
+//
+//	> [!WARNING]
+//	> Back up your data first.
+//
+// or as fenced blocks, with any kind and an optional title:
+//
+//	:::tip Pro tip
+//	Callouts can be **nested**.
+//	:::
+//
+// Each renders as <aside class="admonition admonition-<kind>" role="note">
+// with a title paragraph followed by the rendered body.
+type AdmonitionOptions struct {
+	// Class is set on every callout. Defaults to "admonition". The title and
+	// icon elements get Class+"-title" and Class+"-icon".
+	Class string
+	// Classes overrides the per-kind class, which defaults to Class+"-"+kind.
+	// Kinds are lowercase, e.g. "note" or "warning".
+	Classes map[string]string
+	// Icons holds per-kind markup (e.g. an inline SVG) placed before the
+	// title. Kinds without an icon get none.
+	Icons map[string]template.HTML
+	// Titles overrides the per-kind default title, which is the kind
+	// capitalized ("Note"). A title given on a ::: line wins over both.
+	Titles map[string]string
+}
+
+var (
+	githubAlertRe  = regexp.MustCompile(`(?i)^>\s*\[!(note|tip|important|warning|caution)\]\s*(.*)$`)
+	fencedOpenRe   = regexp.MustCompile(`^:::\s*([A-Za-z][\w-]*)\s*(.*)$`)
+	admonitionStub = "fsmarkdownadmonition%d"
+)
+
+type admonition struct {
+	kind  string
+	title string
+	body  []byte
+}
+
+// extractAdmonitions replaces each callout block in src, outside fenced
+// code blocks, with a placeholder paragraph, returning the rewritten source
+// and the blocks in placeholder order.
+func extractAdmonitions(src []byte) ([]byte, []admonition) {
+	if !bytes.Contains(src, []byte(":::")) && !bytes.Contains(src, []byte("[!")) {
+		return src, nil
+	}
+
+	lines := strings.SplitAfter(string(src), "\n")
+	var out strings.Builder
+	var blocks []admonition
+	var fence string
+
+	emit := func(a admonition) {
+		fmt.Fprintf(&out, "\n"+admonitionStub+"\n\n", len(blocks))
+		blocks = append(blocks, a)
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			out.WriteString(line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			out.WriteString(line)
+			continue
+		}
+
+		if m := githubAlertRe.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil {
+			a := admonition{kind: strings.ToLower(m[1]), title: m[2]}
+			var body strings.Builder
+			for i+1 < len(lines) && strings.HasPrefix(lines[i+1], ">") {
+				i++
+				rest := strings.TrimPrefix(lines[i], ">")
+				body.WriteString(strings.TrimPrefix(rest, " "))
+			}
+			a.body = []byte(body.String())
+			emit(a)
+			continue
+		}
+
+		if m := fencedOpenRe.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil {
+			a := admonition{kind: strings.ToLower(m[1]), title: m[2]}
+			var body strings.Builder
+			depth := 1
+			var innerFence string
+			for i+1 < len(lines) {
+				i++
+				inner := strings.TrimSpace(lines[i])
+				switch {
+				case innerFence != "":
+					if strings.HasPrefix(inner, innerFence) {
+						innerFence = ""
+					}
+				case strings.HasPrefix(inner, "```") || strings.HasPrefix(inner, "~~~"):
+					innerFence = inner[:3]
+				case inner == ":::":
+					depth--
+				case fencedOpenRe.MatchString(inner):
+					depth++
+				}
+				if depth == 0 {
+					break
+				}
+				body.WriteString(lines[i])
+			}
+			a.body = []byte(body.String())
+			emit(a)
+			continue
+		}
+
+		out.WriteString(line)
+	}
+
+	return []byte(out.String()), blocks
+}
+
+// renderAdmonition renders a callout, whose body is itself markdown.
+func (inst *Instance) renderAdmonition(a admonition, bundleURL string) *renderOutput {
+	opts := inst.opts.Admonitions
+	if opts == nil {
+		opts = &AdmonitionOptions{}
+	}
+
+	class := opts.Class
+	if class == "" {
+		class = "admonition"
+	}
+	kindClass := opts.Classes[a.kind]
+	if kindClass == "" {
+		kindClass = class + "-" + a.kind
+	}
+	title := a.title
+	if title == "" {
+		title = opts.Titles[a.kind]
+	}
+	if title == "" {
+		title = strings.ToUpper(a.kind[:1]) + a.kind[1:]
+	}
+
+	body := inst.renderMarkdown(a.body, bundleURL)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<aside class="%s %s" role="note">`+"\n", html.EscapeString(class), html.EscapeString(kindClass))
+	fmt.Fprintf(&buf, `<p class="%s-title">`, html.EscapeString(class))
+	if icon := opts.Icons[a.kind]; icon != "" {
+		fmt.Fprintf(&buf, `<span class="%s-icon" aria-hidden="true">%s</span>`, html.EscapeString(class), icon)
+	}
+	fmt.Fprintf(&buf, "%s</p>\n", html.EscapeString(title))
+	buf.Write(body.html)
+	buf.WriteString("</aside>\n")
+
+	return &renderOutput{html: buf.Bytes(), text: title + "\n\n" + body.text, images: body.images}
+}
+
+// insertAdmonitions swaps the placeholders left by extractAdmonitions for
+// the rendered callouts.
+func (inst *Instance) insertAdmonitions(out *renderOutput, blocks []admonition, bundleURL string) {
+	for i, a := range blocks {
+		stub := fmt.Sprintf(admonitionStub, i)
+		rendered := inst.renderAdmonition(a, bundleURL)
+		out.html = bytes.Replace(out.html, []byte("<p>"+stub+"</p>\n"), rendered.html, 1)
+		out.text = strings.Replace(out.text, stub, rendered.text, 1)
+		out.images = append(out.images, rendered.images...)
+	}
+}
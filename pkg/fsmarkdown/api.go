@@ -0,0 +1,205 @@
+package fsmarkdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/sjc5/kit/pkg/response"
+)
+
+type APIOptions struct {
+	// Path prefix the handler is mounted at. Defaults to "/api/content".
+	Prefix string
+	// Default and maximum number of items per section listing. Default to 20 and 100.
+	DefaultLimit int
+	MaxLimit     int
+}
+
+// Default fields for items in section listings (content is omitted unless
+// explicitly requested via ?fields=).
+var defaultSectionFields = []string{"title", "description", "date", "url", "isFolder"}
+
+type apiSectionResponse struct {
+	Items  []map[string]json.RawMessage `json:"items"`
+	Total  int                          `json:"total"`
+	Limit  int                          `json:"limit"`
+	Offset int                          `json:"offset"`
+}
+
+// APIHandler serves the markdown content as JSON:
+//
+//	{prefix}/pages/{path}     a single page
+//	{prefix}/sections/{path}  the pages in a section (?limit=, ?offset=)
+//	{prefix}/nav/{path}       the nav tree rooted at a section
+//
+// Pages and section items accept ?fields=title,url,... to select fields.
+func (inst *Instance) APIHandler(opts *APIOptions) http.Handler {
+	if opts == nil {
+		opts = &APIOptions{}
+	}
+
+	prefix := strings.TrimSuffix(opts.Prefix, "/")
+	if prefix == "" {
+		prefix = "/api/content"
+	}
+	defaultLimit := opts.DefaultLimit
+	if defaultLimit <= 0 {
+		defaultLimit = 20
+	}
+	maxLimit := opts.MaxLimit
+	if maxLimit <= 0 {
+		maxLimit = 100
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := response.New(w)
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			res.MethodNotAllowed()
+			return
+		}
+
+		rest, ok := strings.CutPrefix(r.URL.Path, prefix+"/")
+		if !ok {
+			res.NotFound()
+			return
+		}
+		kind, contentPath, _ := strings.Cut(rest, "/")
+		cleanPath := path.Clean("/" + contentPath)
+		fields := parseFields(r.URL.Query().Get("fields"))
+
+		switch kind {
+		case "pages":
+			p, found, err := inst.resolvePage(r.Context(), cleanPath)
+			if err != nil {
+				fmt.Println("Error getting page in APIHandler: ", err)
+				res.InternalServerError()
+				return
+			}
+			if !found {
+				res.NotFound()
+				return
+			}
+			item, err := selectFields(p, fields)
+			if err != nil {
+				fmt.Println("Error selecting fields in APIHandler: ", err)
+				res.InternalServerError()
+				return
+			}
+			res.JSON(item)
+
+		case "sections":
+			limit, err := parseIntParam(r, "limit", defaultLimit)
+			if err != nil || limit <= 0 {
+				res.BadRequest("invalid limit")
+				return
+			}
+			limit = min(limit, maxLimit)
+			offset, err := parseIntParam(r, "offset", 0)
+			if err != nil || offset < 0 {
+				res.BadRequest("invalid offset")
+				return
+			}
+
+			pages, _, err := inst.listSection(r.Context(), cleanPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					res.NotFound()
+					return
+				}
+				fmt.Println("Error listing section in APIHandler: ", err)
+				res.InternalServerError()
+				return
+			}
+
+			if fields == nil {
+				fields = defaultSectionFields
+			}
+
+			out := apiSectionResponse{
+				Items:  []map[string]json.RawMessage{},
+				Total:  len(pages),
+				Limit:  limit,
+				Offset: offset,
+			}
+			for _, p := range pages[min(offset, len(pages)):min(offset+limit, len(pages))] {
+				item, err := selectFields(p, fields)
+				if err != nil {
+					fmt.Println("Error selecting fields in APIHandler: ", err)
+					res.InternalServerError()
+					return
+				}
+				out.Items = append(out.Items, item)
+			}
+			res.JSON(out)
+
+		case "nav":
+			tree, err := inst.navTree(r.Context(), cleanPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					res.NotFound()
+					return
+				}
+				fmt.Println("Error getting nav tree in APIHandler: ", err)
+				res.InternalServerError()
+				return
+			}
+			res.JSON(tree)
+
+		default:
+			res.NotFound()
+		}
+	})
+}
+
+func parseFields(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func parseIntParam(r *http.Request, name string, fallback int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(raw)
+}
+
+// selectFields returns p's JSON representation restricted to the given
+// fields. A nil fields slice means all fields.
+func selectFields(p *Page, fields []string) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+
+	if fields == nil {
+		return all, nil
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, f := range fields {
+		if v, ok := all[f]; ok {
+			selected[f] = v
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,145 @@
+package fsmarkdown
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ArchiveYear groups a section's dated pages by year.
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	URL    string         `json:"url"` // e.g. /blog/2024
+	Months []ArchiveMonth `json:"months"`
+}
+
+// ArchiveMonth groups a section's dated pages by month.
+type ArchiveMonth struct {
+	Year  int        `json:"year"`
+	Month time.Month `json:"month"`
+	URL   string     `json:"url"` // e.g. /blog/2024/06
+	Pages []*Page    `json:"pages"`
+}
+
+// Archive returns the pages directly in section that have a parseable
+// frontmatter date, grouped by year and month, newest first. Pages without
+// a date are left out.
+func (inst *Instance) Archive(section string) ([]ArchiveYear, error) {
+	return inst.archive(context.Background(), path.Clean("/"+section))
+}
+
+func (inst *Instance) archive(ctx context.Context, section string) ([]ArchiveYear, error) {
+	pages, _, err := inst.listSection(ctx, section)
+	if err != nil {
+		return nil, err
+	}
+
+	// listSection sorts newest first, which the grouping below relies on.
+	var years []ArchiveYear
+	for _, p := range pages {
+		if p.IsFolder {
+			continue
+		}
+		date, ok := parseDate(p.Date)
+		if !ok {
+			continue
+		}
+
+		if len(years) == 0 || years[len(years)-1].Year != date.Year() {
+			years = append(years, ArchiveYear{
+				Year: date.Year(),
+				URL:  path.Join(section, strconv.Itoa(date.Year())),
+			})
+		}
+		year := &years[len(years)-1]
+
+		if len(year.Months) == 0 || year.Months[len(year.Months)-1].Month != date.Month() {
+			year.Months = append(year.Months, ArchiveMonth{
+				Year:  date.Year(),
+				Month: date.Month(),
+				URL:   path.Join(year.URL, fmt.Sprintf("%02d", date.Month())),
+			})
+		}
+		month := &year.Months[len(year.Months)-1]
+		month.Pages = append(month.Pages, p)
+	}
+
+	return years, nil
+}
+
+var archivePathRe = regexp.MustCompile(`^(.+)/(\d{4})(?:/(\d{2}))?$`)
+
+// archivePage builds the listing served at /<section>/<year> or
+// /<section>/<year>/<month> when no page exists there. It reports false
+// if cleanPath isn't an archive path of a folder, or the period is empty.
+func (inst *Instance) archivePage(ctx context.Context, cleanPath string) (*DetailedPage, bool, error) {
+	m := archivePathRe.FindStringSubmatch(cleanPath)
+	if m == nil {
+		return nil, false, nil
+	}
+	section := m[1]
+	year, _ := strconv.Atoi(m[2])
+	var month time.Month
+	if m[3] != "" {
+		n, _ := strconv.Atoi(m[3])
+		if n < 1 || n > 12 {
+			return nil, false, nil
+		}
+		month = time.Month(n)
+	}
+
+	sectionPage, found, err := inst.getPageBase(ctx, section)
+	if err != nil || !found || !sectionPage.IsFolder {
+		return nil, false, err
+	}
+
+	years, err := inst.archive(ctx, section)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var items Sitemap
+	for _, y := range years {
+		if y.Year != year {
+			continue
+		}
+		for _, mo := range y.Months {
+			if month != 0 && mo.Month != month {
+				continue
+			}
+			for _, p := range mo.Pages {
+				items = append(items, SitemapItem{Title: p.Title, URL: p.URL})
+			}
+		}
+	}
+	if len(items) == 0 {
+		return nil, false, nil
+	}
+
+	title := fmt.Sprintf("%s: %d", sectionPage.Title, year)
+	backItem := section
+	if month != 0 {
+		title = fmt.Sprintf("%s: %s %d", sectionPage.Title, month, year)
+		backItem = path.Join(section, m[2])
+	}
+
+	sm, err := inst.generateSitemap(ctx, generateSitemapInput{CleanPath: section, IsIndex: false})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &DetailedPage{
+		Page: &Page{
+			Title:      title,
+			URL:        cleanPath,
+			SourcePath: cleanPath,
+			IsFolder:   true,
+		},
+		Sitemap:      sm.Sitemap,
+		IndexSitemap: items,
+		BackItem:     backItem,
+	}, true, nil
+}
@@ -0,0 +1,151 @@
+package fsmarkdown
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// BundleFileName is the name of the precompiled content bundle, relative
+// to the root of Instance.FS (i.e. a sibling of the markdown directory).
+const BundleFileName = "fsmarkdown_bundle.gob.gz"
+
+// Bump whenever the bundle's shape changes, so stale bundles are ignored.
+const bundleVersion = 6
+
+type bundle struct {
+	Version  int
+	Pages    map[string]*Page
+	Sections map[string]*bundleSection
+	Nav      *NavNode
+	Search   []*searchDoc
+}
+
+type bundleSection struct {
+	Paths    []string // source paths
+	HasIndex bool
+}
+
+// WriteBundle parses every page once (bypassing any loaded bundle) and
+// writes a compressed bundle of pages, section listings, the nav tree and
+// the search index to w.
+func (inst *Instance) WriteBundle(w io.Writer) error {
+	opts := inst.opts
+	opts.DisableBundle = true
+	live := NewWithOptions(&opts)
+	live.deferImageURLs = true
+	ctx := context.Background()
+
+	paths, err := live.pagePaths()
+	if err != nil {
+		return err
+	}
+
+	b := &bundle{
+		Version:  bundleVersion,
+		Pages:    map[string]*Page{},
+		Sections: map[string]*bundleSection{},
+	}
+
+	dirs := map[string]bool{"/": true}
+	for _, cleanPath := range paths {
+		p, found, err := live.getPageBase(ctx, cleanPath)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		b.Pages[cleanPath] = p
+		b.Search = append(b.Search, newSearchDoc(p))
+		dirs[path.Dir(cleanPath)] = true
+		if p.IsFolder {
+			dirs[cleanPath] = true
+		}
+	}
+
+	for dir := range dirs {
+		pages, hasIndex, err := live.listSection(ctx, dir)
+		if err != nil {
+			return err
+		}
+		section := &bundleSection{HasIndex: hasIndex}
+		for _, p := range pages {
+			section.Paths = append(section.Paths, p.SourcePath)
+		}
+		b.Sections[dir] = section
+	}
+
+	if b.Nav, err = live.navTree(ctx, "/"); err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := gob.NewEncoder(zw).Encode(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// loadBundle reads the bundle from fsys. It returns nil, nil if there is
+// no bundle, or if the bundle was written by an incompatible version.
+func loadBundle(fsys fs.FS) (*bundle, error) {
+	f, err := fsys.Open(BundleFileName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	var b bundle
+	if err := gob.NewDecoder(zr).Decode(&b); err != nil {
+		return nil, err
+	}
+	if b.Version != bundleVersion {
+		fmt.Printf("Ignoring fsmarkdown bundle with version %d (want %d)\n", b.Version, bundleVersion)
+		return nil, nil
+	}
+
+	return &b, nil
+}
+
+func (b *bundle) listSection(dir string) ([]*Page, bool, error) {
+	section, ok := b.Sections[dir]
+	if !ok {
+		return nil, false, &fs.PathError{Op: "readdir", Path: path.Join("markdown", dir), Err: fs.ErrNotExist}
+	}
+
+	pages := make([]*Page, 0, len(section.Paths))
+	for _, sourcePath := range section.Paths {
+		if p, ok := b.Pages[sourcePath]; ok {
+			pages = append(pages, p)
+		}
+	}
+
+	return pages, section.HasIndex, nil
+}
+
+func (n *NavNode) find(url string) *NavNode {
+	if n.URL == url {
+		return n
+	}
+	for _, child := range n.Children {
+		if found := child.find(url); found != nil {
+			return found
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/_index.md":           {Data: []byte("---\ntitle: Home\n---\n# Welcome\n\nHello gophers")},
+		"markdown/docs/_index.md":      {Data: []byte("---\ntitle: Docs\n---\nAll the docs")},
+		"markdown/docs/setup.md":       {Data: []byte("---\ntitle: Setup\nslug: install\n---\n## Steps\n\nRun the installer")},
+		"markdown/docs/untitled.md":    {Data: []byte("No frontmatter here")},
+		"markdown/docs/deep/_index.md": {Data: []byte("---\ntitle: Deep\n---\n")},
+		"markdown/docs/deep/page.md":   {Data: []byte("---\ntitle: Deep page\ndate: 2024-01-02\n---\nDown here")},
+	}
+
+	live := NewWithOptions(&Options{FS: fsys, DisableBundle: true})
+	var buf bytes.Buffer
+	if err := live.WriteBundle(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	bundled := fstest.MapFS{BundleFileName: {Data: buf.Bytes()}}
+	inst := New(bundled)
+	if inst.bundle == nil {
+		t.Fatal("bundle was not loaded")
+	}
+
+	ctx := context.Background()
+	for _, urlPath := range []string{"/", "/docs", "/docs/install", "/docs/untitled", "/docs/deep", "/docs/deep/page"} {
+		t.Run(urlPath, func(t *testing.T) {
+			want, err := live.getPageDetails(ctx, urlPath, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := inst.getPageDetails(ctx, urlPath, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want.IsNotFound() || got.IsNotFound() {
+				t.Fatalf("not found: live %v, bundled %v", want.IsNotFound(), got.IsNotFound())
+			}
+			if got.Title != want.Title || got.URL != want.URL || got.Content != want.Content || got.Description != want.Description {
+				t.Errorf("bundled page = %q %q %q, live = %q %q %q", got.Title, got.URL, got.Content, want.Title, want.URL, want.Content)
+			}
+			if !reflect.DeepEqual(got.Sitemap, want.Sitemap) || !reflect.DeepEqual(got.IndexSitemap, want.IndexSitemap) {
+				t.Errorf("bundled sitemaps = %+v %+v, live = %+v %+v", got.Sitemap, got.IndexSitemap, want.Sitemap, want.IndexSitemap)
+			}
+		})
+	}
+
+	wantNav, err := live.NavTree("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotNav, err := inst.NavTree("/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotNav, wantNav) {
+		t.Errorf("bundled nav = %+v, live = %+v", gotNav, wantNav)
+	}
+
+	for _, query := range []string{"installer", "steps", "gophers", "untitled"} {
+		want, _ := live.Search(query, 5)
+		got, err := inst.Search(query, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) || len(got) == 0 {
+			t.Errorf("bundled Search(%q) = %+v, live = %+v", query, got, want)
+		}
+	}
+}
+
+func TestHeadingIDs(t *testing.T) {
+	fsys := fstest.MapFS{"markdown/page.md": {Data: []byte("## Getting started\n\n## Getting started")}}
+
+	p, _, err := New(fsys).getPageBase(context.Background(), "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(p.Content), "id=") || p.TOC[0].ID != "" {
+		t.Errorf("headings have ids by default: %q %+v", p.Content, p.TOC)
+	}
+
+	p, _, err = NewWithOptions(&Options{FS: fsys, HeadingIDs: true}).getPageBase(context.Background(), "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"getting-started", "getting-started-1"} {
+		if !strings.Contains(string(p.Content), `id="`+id+`"`) {
+			t.Errorf("content %q has no heading id %q", p.Content, id)
+		}
+	}
+	if len(p.TOC) != 2 || p.TOC[0].ID != "getting-started" || p.TOC[1].ID != "getting-started-1" {
+		t.Errorf("TOC = %+v, want ids matching the rendered headings", p.TOC)
+	}
+}
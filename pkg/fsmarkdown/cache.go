@@ -0,0 +1,123 @@
+package fsmarkdown
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultCacheSize = 1_000
+
+// cache is an LRU cache with the same "spam" semantics as kit's lru package
+// (spam items are stored but never promoted), plus hit / miss / eviction
+// counters and the ability to be cleared.
+type cache[K comparable, V any] struct {
+	mu       sync.Mutex
+	items    map[K]*list.Element
+	order    *list.List
+	maxItems int
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type cacheItem[K comparable, V any] struct {
+	key    K
+	value  V
+	isSpam bool
+}
+
+type CacheStats struct {
+	Capacity  int    `json:"capacity"`
+	Size      int    `json:"size"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+func newCache[K comparable, V any](maxItems int) *cache[K, V] {
+	if maxItems <= 0 {
+		maxItems = defaultCacheSize
+	}
+	return &cache[K, V]{
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+		maxItems: maxItems,
+	}
+}
+
+func (c *cache[K, V]) Get(key K) (v V, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, found := c.items[key]
+	if !found {
+		c.misses.Add(1)
+		return v, false
+	}
+
+	c.hits.Add(1)
+	itm := el.Value.(*cacheItem[K, V])
+	if !itm.isSpam {
+		c.order.MoveToFront(el)
+	}
+	return itm.value, true
+}
+
+func (c *cache[K, V]) Set(key K, value V, isSpam bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.items[key]; found {
+		itm := el.Value.(*cacheItem[K, V])
+		itm.value = value
+		itm.isSpam = isSpam
+		if !isSpam {
+			c.order.MoveToFront(el)
+		}
+		return
+	}
+
+	if len(c.items) >= c.maxItems {
+		if back := c.order.Back(); back != nil {
+			delete(c.items, back.Value.(*cacheItem[K, V]).key)
+			c.order.Remove(back)
+			c.evictions.Add(1)
+		}
+	}
+
+	c.items[key] = c.order.PushFront(&cacheItem[K, V]{key: key, value: value, isSpam: isSpam})
+}
+
+func (c *cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, found := c.items[key]; found {
+		delete(c.items, key)
+		c.order.Remove(el)
+	}
+}
+
+func (c *cache[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[K]*list.Element)
+	c.order.Init()
+}
+
+func (c *cache[K, V]) Stats() CacheStats {
+	c.mu.Lock()
+	size := len(c.items)
+	c.mu.Unlock()
+
+	return CacheStats{
+		Capacity:  c.maxItems,
+		Size:      size,
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+	}
+}
@@ -0,0 +1,87 @@
+package fsmarkdown
+
+import "testing"
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newCache[string, int](2)
+	c.Set("a", 1, false)
+	c.Set("b", 2, false)
+	c.Get("a") // a is now the most recently used
+	c.Set("c", 3, false)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("%s should still be cached", k)
+		}
+	}
+	if got := c.Stats().Evictions; got != 1 {
+		t.Errorf("evictions = %d, want 1", got)
+	}
+}
+
+func TestCacheDoesNotPromoteSpam(t *testing.T) {
+	c := newCache[string, int](2)
+	c.Set("spam", 1, true)
+	c.Set("b", 2, false)
+	if v, ok := c.Get("spam"); !ok || v != 1 {
+		t.Fatalf("Get(spam) = %d, %v, want 1, true", v, ok)
+	}
+	c.Set("c", 3, false)
+
+	if _, ok := c.Get("spam"); ok {
+		t.Error("spam should have been evicted despite being read")
+	}
+	if _, ok := c.Get("b"); !ok {
+		t.Error("b should still be cached")
+	}
+}
+
+func TestCacheSetReplacesSpamFlag(t *testing.T) {
+	c := newCache[string, int](2)
+	c.Set("a", 1, false)
+	c.Set("b", 2, false)
+	c.Set("a", 10, true) // a stays least recently used
+	c.Set("c", 3, false)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been evicted")
+	}
+
+	c.Set("b", 20, true)
+	c.Set("b", 21, false) // b is promoted again
+	c.Set("d", 4, false)
+	if v, ok := c.Get("b"); !ok || v != 21 {
+		t.Errorf("Get(b) = %d, %v, want 21, true", v, ok)
+	}
+}
+
+func TestCacheDeleteAndClear(t *testing.T) {
+	c := newCache[string, int](0)
+	if got := c.Stats().Capacity; got != defaultCacheSize {
+		t.Errorf("capacity = %d, want %d", got, defaultCacheSize)
+	}
+
+	c.Set("a", 1, false)
+	c.Set("b", 2, true)
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should have been deleted")
+	}
+
+	c.Clear()
+	if _, ok := c.Get("b"); ok {
+		t.Error("b should have been cleared")
+	}
+	c.Set("c", 3, false)
+	if v, ok := c.Get("c"); !ok || v != 3 {
+		t.Errorf("Get(c) after Clear = %d, %v, want 3, true", v, ok)
+	}
+
+	want := CacheStats{Capacity: defaultCacheSize, Size: 1, Hits: 1, Misses: 2}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,238 @@
+package fsmarkdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// dataExtensions lists the supported data file extensions, in lookup order.
+var dataExtensions = []string{".yaml", ".yml", ".json", ".toml"}
+
+// Data returns the decoded contents of the data file data/<name>.{yaml,yml,
+// json,toml} (name may contain slashes, e.g. "company/team"). Maps are
+// returned as map[string]any and lists as []any. Results are cached until
+// Invalidate is called.
+func (inst *Instance) Data(name string) (any, error) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+
+	inst.dataMu.Lock()
+	defer inst.dataMu.Unlock()
+
+	if v, ok := inst.data[name]; ok {
+		return v, nil
+	}
+
+	var v any
+	if err := inst.DecodeData(name, &v); err != nil {
+		return nil, err
+	}
+	v = normalizeData(v)
+	inst.data[name] = v
+
+	return v, nil
+}
+
+// DecodeData decodes the data file data/<name>.{yaml,yml,json,toml} into v,
+// which should be a pointer, using the decoder for the file's format. Unlike
+// Data, results are not cached.
+func (inst *Instance) DecodeData(name string, v any) error {
+	name = strings.Trim(path.Clean("/"+name), "/")
+
+	for _, ext := range dataExtensions {
+		filePath := path.Join("data", name+ext)
+		fileBytes, err := fs.ReadFile(inst.FS, filePath)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		switch ext {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(fileBytes, v)
+		case ".json":
+			err = json.Unmarshal(fileBytes, v)
+		case ".toml":
+			err = toml.Unmarshal(fileBytes, v)
+		}
+		if err != nil {
+			return fmt.Errorf("fsmarkdown: parsing %s: %w", filePath, err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("fsmarkdown: no data file for %q in data/: %w", name, fs.ErrNotExist)
+}
+
+// lookupData resolves a reference like "company/team.members.0.name": the
+// part before the first dot names the data file, the rest indexes into it.
+func (inst *Instance) lookupData(ref string) (any, error) {
+	name, keyPath, _ := strings.Cut(ref, ".")
+
+	v, err := inst.Data(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyPath == "" {
+		return v, nil
+	}
+	for _, key := range strings.Split(keyPath, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("fsmarkdown: data %q has no key %q", ref, key)
+			}
+			v = next
+		case []any:
+			var i int
+			if _, err := fmt.Sscanf(key, "%d", &i); err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("fsmarkdown: data %q has no index %q", ref, key)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("fsmarkdown: data %q: cannot index %T with %q", ref, v, key)
+		}
+	}
+
+	return v, nil
+}
+
+// dataRefFile returns the data file (within FS) that a lookupData
+// reference reads from, if it exists.
+func (inst *Instance) dataRefFile(ref string) (string, bool) {
+	name, _, _ := strings.Cut(ref, ".")
+	name = strings.Trim(path.Clean("/"+name), "/")
+	for _, ext := range dataExtensions {
+		filePath := path.Join("data", name+ext)
+		if _, err := fs.Stat(inst.FS, filePath); err == nil {
+			return filePath, true
+		}
+	}
+	return "", false
+}
+
+// normalizeData converts the map[interface{}]interface{} values produced by
+// yaml.v2 into map[string]any, so every format decodes to the same shapes.
+func normalizeData(v any) any {
+	switch node := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(node))
+		for k, val := range node {
+			m[fmt.Sprint(k)] = normalizeData(val)
+		}
+		return m
+	case map[string]any:
+		for k, val := range node {
+			node[k] = normalizeData(val)
+		}
+		return node
+	case []any:
+		for i, val := range node {
+			node[i] = normalizeData(val)
+		}
+		return node
+	case []map[string]any:
+		list := make([]any, len(node))
+		for i, val := range node {
+			list[i] = normalizeData(val)
+		}
+		return list
+	default:
+		return v
+	}
+}
+
+// dataToMarkdown renders a data value as markdown: scalars as text, lists
+// of scalars as bullet lists, and maps or lists of maps as tables. For
+// tables, columns picks and orders the columns; by default every key is
+// shown, sorted.
+func dataToMarkdown(v any, columns []string) string {
+	switch node := v.(type) {
+	case map[string]any:
+		keys := columns
+		if len(keys) == 0 {
+			keys = sortedKeys(node)
+		}
+		var sb strings.Builder
+		sb.WriteString("| Key | Value |\n| --- | --- |\n")
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "| %s | %s |\n", tableCell(k), tableCell(node[k]))
+		}
+		return sb.String()
+
+	case []any:
+		if len(node) == 0 {
+			return ""
+		}
+		if _, ok := node[0].(map[string]any); !ok {
+			var sb strings.Builder
+			for _, item := range node {
+				fmt.Fprintf(&sb, "- %v\n", item)
+			}
+			return sb.String()
+		}
+
+		keys := columns
+		if len(keys) == 0 {
+			for _, item := range node {
+				if m, ok := item.(map[string]any); ok {
+					for _, k := range sortedKeys(m) {
+						if !slices.Contains(keys, k) {
+							keys = append(keys, k)
+						}
+					}
+				}
+			}
+		}
+		var sb strings.Builder
+		sb.WriteString("|")
+		for _, k := range keys {
+			sb.WriteString(" " + tableCell(k) + " |")
+		}
+		sb.WriteString("\n|" + strings.Repeat(" --- |", len(keys)) + "\n")
+		for _, item := range node {
+			m, _ := item.(map[string]any)
+			sb.WriteString("|")
+			for _, k := range keys {
+				sb.WriteString(" " + tableCell(m[k]) + " |")
+			}
+			sb.WriteString("\n")
+		}
+		return sb.String()
+
+	case nil:
+		return ""
+
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func tableCell(v any) string {
+	if v == nil {
+		return ""
+	}
+	s := strings.ReplaceAll(fmt.Sprint(v), "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
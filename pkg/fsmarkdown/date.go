@@ -0,0 +1,31 @@
+package fsmarkdown
+
+import (
+	"strings"
+	"time"
+)
+
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"January 2, 2006",
+	"Jan 2, 2006",
+}
+
+// parseDate parses the frontmatter date formats we accept. Returns false
+// if the string is empty or in an unrecognized format.
+func parseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,425 @@
+package fsmarkdown
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sjc5/kit/pkg/response"
+)
+
+// EPUBOptions configures WriteEPUB.
+type EPUBOptions struct {
+	// Title of the book. Defaults to the section's title.
+	Title string
+	// Authors, listed as dc:creator. Defaults to the pages' Authors (see
+	// Options.History), in order of appearance.
+	Authors []string
+	// Language tag. Defaults to "en".
+	Language string
+	// Identifier, e.g. an ISBN URN. Defaults to a UUID derived from the
+	// title and section.
+	Identifier string
+	// Modified is the book's dcterms:modified. Defaults to the newest page
+	// date (see Page.LastModified), or the current time if no page has one.
+	Modified time.Time
+	// PublicFS is the public static dir, used to embed local images (see
+	// ImageOptions) other than page bundle assets. Images that can't be
+	// embedded are replaced by their alt text.
+	PublicFS fs.FS
+	// BaseURL makes links to pages outside the book absolute.
+	BaseURL string
+	// CSS, if set, is included as a stylesheet for every chapter.
+	CSS string
+}
+
+type epubChapter struct {
+	page     *Page
+	file     string
+	children []*epubChapter
+}
+
+type epubImage struct {
+	id, file, mediaType string
+	data                []byte
+}
+
+var epubImageMediaTypes = map[string]string{
+	".gif":  "image/gif",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+}
+
+var (
+	epubImgRe    = regexp.MustCompile(`<img\b[^>]*>`)
+	epubSourceRe = regexp.MustCompile(`</?picture>|<source\b[^>]*>`)
+	epubSrcRe    = regexp.MustCompile(`\ssrc="([^"]*)"`)
+	epubAltRe    = regexp.MustCompile(`\salt="([^"]*)"`)
+	epubHrefRe   = regexp.MustCompile(`\shref="(/[^"]*)"`)
+	epubEntityRe = regexp.MustCompile(`&([A-Za-z][A-Za-z0-9]*);`)
+)
+
+// WriteEPUB writes section (a folder's clean URL path, or "/") as an EPUB 3
+// book to w: the folder's own page, then every page below it, in sitemap
+// order, one XHTML chapter each. The nav document mirrors the folder
+// structure. Links between pages in the book point to their chapters.
+//
+// Pages are parsed fresh (bypassing any loaded bundle) without responsive
+// images. Raw HTML in markdown is copied as-is, so it must be well-formed
+// XHTML for the book to be valid.
+func (inst *Instance) WriteEPUB(w io.Writer, section string, opts *EPUBOptions) error {
+	if opts == nil {
+		opts = &EPUBOptions{}
+	}
+
+	liveOpts := inst.opts
+	liveOpts.DisableBundle = true
+	liveOpts.Images = nil
+	live := NewWithOptions(&liveOpts)
+	ctx := context.Background()
+
+	nav, err := live.navTree(ctx, section)
+	if err != nil {
+		return err
+	}
+
+	var spine []*epubChapter
+	byURL := map[string]*epubChapter{}
+	var collect func(node *NavNode) (*epubChapter, error)
+	collect = func(node *NavNode) (*epubChapter, error) {
+		ch := &epubChapter{}
+		p, found, err := live.navPage(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			ch.page = p
+			ch.file = fmt.Sprintf("chapter-%d.xhtml", len(spine)+1)
+			spine = append(spine, ch)
+			byURL[p.URL] = ch
+		}
+		for _, child := range node.Children {
+			c, err := collect(child)
+			if err != nil {
+				return nil, err
+			}
+			ch.children = append(ch.children, c)
+		}
+		return ch, nil
+	}
+	root, err := collect(nav)
+	if err != nil {
+		return err
+	}
+	if len(spine) == 0 {
+		return fmt.Errorf("fsmarkdown: section %s has no pages", nav.URL)
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = nav.Title
+	}
+	lang := opts.Language
+	if lang == "" {
+		lang = "en"
+	}
+	identifier := opts.Identifier
+	if identifier == "" {
+		sum := sha1.Sum([]byte(title + "\x00" + nav.URL))
+		sum[6] = sum[6]&0x0f | 0x50
+		sum[8] = sum[8]&0x3f | 0x80
+		identifier = fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	}
+	authors := opts.Authors
+	modified := opts.Modified
+	if len(authors) == 0 || modified.IsZero() {
+		seen := map[string]bool{}
+		var newest time.Time
+		for _, ch := range spine {
+			for _, a := range ch.page.Authors {
+				if !seen[a] {
+					seen[a] = true
+					if len(opts.Authors) == 0 {
+						authors = append(authors, a)
+					}
+				}
+			}
+			if d := ch.page.effectiveDate(); d.After(newest) {
+				newest = d
+			}
+		}
+		if modified.IsZero() {
+			modified = newest
+		}
+		if modified.IsZero() {
+			modified = time.Now()
+		}
+	}
+
+	images := map[string]*epubImage{}
+	var imageOrder []*epubImage
+
+	zw := zip.NewWriter(w)
+
+	// The mimetype must come first, uncompressed
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	io.WriteString(mw, "application/epub+zip")
+
+	if err := writeZipFile(zw, "META-INF/container.xml", []byte(epubContainerXML)); err != nil {
+		return err
+	}
+
+	svgChapters := map[string]bool{}
+	for _, ch := range spine {
+		content := string(ch.page.Content)
+
+		// Only the <img> fallback of a <picture> is embedded
+		content = epubSourceRe.ReplaceAllString(content, "")
+		content = epubImgRe.ReplaceAllStringFunc(content, func(tag string) string {
+			var alt string
+			if m := epubAltRe.FindStringSubmatch(tag); m != nil {
+				alt = m[1]
+			}
+			m := epubSrcRe.FindStringSubmatch(tag)
+			if m == nil {
+				return alt
+			}
+			publicPath, ok := localImagePath(html.UnescapeString(m[1]))
+			if !ok {
+				return alt
+			}
+			img := images[publicPath]
+			if img == nil {
+				mediaType := epubImageMediaTypes[strings.ToLower(path.Ext(publicPath))]
+				if mediaType == "" {
+					return alt
+				}
+				data, err := live.epubImageData(ctx, opts.PublicFS, publicPath)
+				if err != nil {
+					fmt.Printf("Skipping image %s referenced by %s: %v\n", publicPath, ch.page.FilePath, err)
+					return alt
+				}
+				img = &epubImage{
+					id:        fmt.Sprintf("image-%d", len(imageOrder)+1),
+					file:      "images/" + publicPath,
+					mediaType: mediaType,
+					data:      data,
+				}
+				images[publicPath] = img
+				imageOrder = append(imageOrder, img)
+			}
+			return fmt.Sprintf(`<img src="%s" alt="%s" />`, html.EscapeString(epubHref(img.file)), alt)
+		})
+
+		content = epubHrefRe.ReplaceAllStringFunc(content, func(attr string) string {
+			href := html.UnescapeString(epubHrefRe.FindStringSubmatch(attr)[1])
+			target, fragment, hasFragment := strings.Cut(href, "#")
+			if linked := byURL[path.Clean(target)]; linked != nil {
+				href = linked.file
+				if hasFragment {
+					href += "#" + fragment
+				}
+			} else if opts.BaseURL != "" {
+				href = strings.TrimSuffix(opts.BaseURL, "/") + href
+			}
+			return fmt.Sprintf(` href="%s"`, html.EscapeString(href))
+		})
+
+		content = xhtmlEntities(content)
+		if strings.Contains(content, "<svg") {
+			svgChapters[ch.file] = true
+		}
+
+		var buf bytes.Buffer
+		writeXHTMLHead(&buf, ch.page.Title, lang, opts.CSS != "")
+		fmt.Fprintf(&buf, "<section epub:type=\"chapter\">\n<h1>%s</h1>\n%s\n</section>\n</body>\n</html>\n", html.EscapeString(ch.page.Title), content)
+		if err := writeZipFile(zw, "OEBPS/"+ch.file, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	var navBuf bytes.Buffer
+	writeXHTMLHead(&navBuf, title, lang, opts.CSS != "")
+	fmt.Fprintf(&navBuf, "<nav epub:type=\"toc\" id=\"toc\">\n<h1>%s</h1>\n", html.EscapeString(title))
+	// The section's own page is listed first, at the same level as its children
+	navItems := root.children
+	if root.page != nil {
+		navItems = append([]*epubChapter{{page: root.page, file: root.file}}, navItems...)
+	}
+	writeEPUBNav(&navBuf, navItems)
+	navBuf.WriteString("</nav>\n</body>\n</html>\n")
+	if err := writeZipFile(zw, "OEBPS/nav.xhtml", navBuf.Bytes()); err != nil {
+		return err
+	}
+
+	if opts.CSS != "" {
+		if err := writeZipFile(zw, "OEBPS/style.css", []byte(opts.CSS)); err != nil {
+			return err
+		}
+	}
+	for _, img := range imageOrder {
+		if err := writeZipFile(zw, "OEBPS/"+img.file, img.data); err != nil {
+			return err
+		}
+	}
+
+	var opf bytes.Buffer
+	fmt.Fprintf(&opf, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<package xmlns=\"http://www.idpf.org/2007/opf\" version=\"3.0\" unique-identifier=\"bookid\" xml:lang=\"%s\">\n", html.EscapeString(lang))
+	opf.WriteString("<metadata xmlns:dc=\"http://purl.org/dc/elements/1.1/\">\n")
+	fmt.Fprintf(&opf, "<dc:identifier id=\"bookid\">%s</dc:identifier>\n", html.EscapeString(identifier))
+	fmt.Fprintf(&opf, "<dc:title>%s</dc:title>\n", html.EscapeString(title))
+	fmt.Fprintf(&opf, "<dc:language>%s</dc:language>\n", html.EscapeString(lang))
+	for _, a := range authors {
+		fmt.Fprintf(&opf, "<dc:creator>%s</dc:creator>\n", html.EscapeString(a))
+	}
+	if root.page != nil && root.page.Description != "" {
+		fmt.Fprintf(&opf, "<dc:description>%s</dc:description>\n", html.EscapeString(root.page.Description))
+	}
+	fmt.Fprintf(&opf, "<meta property=\"dcterms:modified\">%s</meta>\n", modified.UTC().Format("2006-01-02T15:04:05Z"))
+	opf.WriteString("</metadata>\n<manifest>\n")
+	opf.WriteString("<item id=\"nav\" href=\"nav.xhtml\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n")
+	if opts.CSS != "" {
+		opf.WriteString("<item id=\"css\" href=\"style.css\" media-type=\"text/css\"/>\n")
+	}
+	for i, ch := range spine {
+		var props string
+		if svgChapters[ch.file] {
+			props = ` properties="svg"`
+		}
+		fmt.Fprintf(&opf, "<item id=\"chapter-%d\" href=\"%s\" media-type=\"application/xhtml+xml\"%s/>\n", i+1, ch.file, props)
+	}
+	for _, img := range imageOrder {
+		fmt.Fprintf(&opf, "<item id=\"%s\" href=\"%s\" media-type=\"%s\"/>\n", img.id, html.EscapeString(epubHref(img.file)), img.mediaType)
+	}
+	opf.WriteString("</manifest>\n<spine>\n")
+	for i := range spine {
+		fmt.Fprintf(&opf, "<itemref idref=\"chapter-%d\"/>\n", i+1)
+	}
+	opf.WriteString("</spine>\n</package>\n")
+	if err := writeZipFile(zw, "OEBPS/content.opf", opf.Bytes()); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// EPUBHandler serves WriteEPUB's output as a download.
+func (inst *Instance) EPUBHandler(section string, opts *EPUBOptions) http.Handler {
+	fileName := strings.Trim(path.Clean("/"+section), "/")
+	if fileName == "" {
+		fileName = "index"
+	}
+	fileName = strings.ReplaceAll(fileName, "/", "-") + ".epub"
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := inst.WriteEPUB(&buf, section, opts); err != nil {
+			fmt.Println("Error writing EPUB in EPUBHandler: ", err)
+			response.New(w).InternalServerError()
+			return
+		}
+		w.Header().Set("Content-Type", "application/epub+zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+		w.Write(buf.Bytes())
+	})
+}
+
+// epubImageData reads a local image from a page bundle (see
+// bundleIndexName) or, failing that, from publicFS.
+func (inst *Instance) epubImageData(ctx context.Context, publicFS fs.FS, publicPath string) ([]byte, error) {
+	filePath, found, err := inst.bundleAsset(ctx, "/"+publicPath)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return fs.ReadFile(inst.FS, filePath)
+	}
+	if publicFS == nil {
+		return nil, fs.ErrNotExist
+	}
+	return fs.ReadFile(publicFS, publicPath)
+}
+
+const epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles>
+<rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+</rootfiles>
+</container>
+`
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+func writeXHTMLHead(buf *bytes.Buffer, title, lang string, css bool) {
+	fmt.Fprintf(buf, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE html>\n<html xmlns=\"http://www.w3.org/1999/xhtml\" xmlns:epub=\"http://www.idpf.org/2007/ops\" xml:lang=\"%[1]s\" lang=\"%[1]s\">\n<head>\n<meta charset=\"UTF-8\"/>\n<title>%[2]s</title>\n", html.EscapeString(lang), html.EscapeString(title))
+	if css {
+		buf.WriteString("<link rel=\"stylesheet\" type=\"text/css\" href=\"style.css\"/>\n")
+	}
+	buf.WriteString("</head>\n<body>\n")
+}
+
+func writeEPUBNav(buf *bytes.Buffer, chapters []*epubChapter) {
+	buf.WriteString("<ol>\n")
+	for _, ch := range chapters {
+		if ch.page == nil {
+			continue
+		}
+		fmt.Fprintf(buf, "<li><a href=\"%s\">%s</a>", ch.file, html.EscapeString(ch.page.Title))
+		if slices.ContainsFunc(ch.children, func(c *epubChapter) bool { return c.page != nil }) {
+			buf.WriteString("\n")
+			writeEPUBNav(buf, ch.children)
+		}
+		buf.WriteString("</li>\n")
+	}
+	buf.WriteString("</ol>\n")
+}
+
+// epubHref escapes each segment of a path inside the book.
+func epubHref(file string) string {
+	return (&url.URL{Path: file}).EscapedPath()
+}
+
+// xhtmlEntities replaces named HTML entities (e.g. the &rsquo; produced by
+// smartypants), which XHTML doesn't define, with numeric references.
+func xhtmlEntities(s string) string {
+	return epubEntityRe.ReplaceAllStringFunc(s, func(entity string) string {
+		switch entity {
+		case "&amp;", "&lt;", "&gt;", "&quot;", "&apos;":
+			return entity
+		}
+		decoded := html.UnescapeString(entity)
+		if decoded == entity {
+			return "&amp;" + entity[1:]
+		}
+		var sb strings.Builder
+		for _, r := range decoded {
+			fmt.Fprintf(&sb, "&#%d;", r)
+		}
+		return sb.String()
+	})
+}
@@ -2,47 +2,252 @@ package fsmarkdown
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	texttemplate "text/template"
+	"time"
 
 	"github.com/adrg/frontmatter"
-	"github.com/russross/blackfriday/v2"
-	"github.com/sjc5/kit/pkg/lru"
-	"github.com/sjc5/kit/pkg/typed"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
-// Do not initialize manually. Always create with New().
+// Do not initialize manually. Always create with New() or NewWithOptions().
 type Instance struct {
 	FS               fs.FS
-	pageDetailsCache *lru.Cache[string, *DetailedPage]
-	sitemapCache     typed.SyncMap[generateSitemapInput, *generateSitemapInnerData]
-	basePageCache    *lru.Cache[string, *Page]
+	opts             Options
+	pageDetailsCache *cache[string, *DetailedPage]
+	sitemapCache     *cache[generateSitemapInput, *generateSitemapInnerData]
+	basePageCache    *cache[string, *Page]
+	bundle           *bundle
+	parseSem         chan struct{}
+	pageDetailsGroup singleflight.Group
+	pageBaseGroup    singleflight.Group
+	searchIndexMu    sync.Mutex
+	searchIndex      []*searchDoc
+	redirectsMu      sync.Mutex
+	redirects        map[string]string
+	redirectsErr     error
+	redirectsGroup   singleflight.Group
+	redirectsLogOnce sync.Once
+	relatedMu        sync.Mutex
+	related          map[string][]relatedCandidate
+	permalinkIndexMu sync.Mutex
+	permalinkIndex   map[string]string
+	permalinkGroup   singleflight.Group
+	llmsMu           sync.Mutex
+	llms             map[llmsKey][]byte
+	bundleDirsMu     sync.Mutex
+	bundleDirs       map[string]bool // page bundle dirs within FS
+	bundleDirsErr    error
+	dataMu           sync.Mutex
+	data             map[string]any
+	dependentsMu     sync.Mutex
+	dependents       map[string]map[string]bool // included file path -> page paths
+	imageManifestMu  sync.Mutex
+	imageManifest    map[string]*imageInfo
+	deferImageURLs   bool // set while writing a bundle
+	bundleImagesOnce sync.Once
+}
+
+type Options struct {
+	// Must contain a "markdown" directory.
+	FS fs.FS
+	// Frontmatter schemas keyed by section URL path (e.g. "/blog"). The most
+	// specific matching section applies; "/" matches every page.
+	Schemas map[string]*Schema
+	// If true, pages with invalid frontmatter fail to load instead of having
+	// their problems logged, a page that fails to render fails the listings
+	// it is in instead of being skipped, and MustValidate panics on any
+	// invalid page.
+	StrictFrontmatter bool
+	// Cache capacities (number of entries). Zero means the default of 1,000.
+	PageDetailsCacheSize int
+	SitemapCacheSize     int
+	BasePageCacheSize    int
+	// If true, a precompiled bundle (see WriteBundle) in FS is ignored and
+	// pages are always parsed from the markdown directory.
+	DisableBundle bool
+	// Maximum number of files read and parsed at once, across all requests,
+	// and of directory entries processed at once per listing. Zero means
+	// runtime.NumCPU().
+	MaxConcurrency int
+	// Page size for folder listings (DetailedPage.IndexSitemap) when the
+	// folder's _index.md doesn't set page_size. Zero means no pagination.
+	DefaultPageSize int
+	// Permalink patterns keyed by section URL path, e.g.
+	// {"/blog": "/blog/:year/:month/:slug"}. The most specific matching
+	// section applies. Supported tokens are :year, :month and :day (from the
+	// page's date), :slug (the "slug" frontmatter, or the file name) and
+	// :filename. Folder pages always keep their file-based URL.
+	Permalinks map[string]string
+	// History, if set, fills in each page's LastModified and Authors (see
+	// NewGitHistory). Pages without a date are then sorted by LastModified.
+	History HistoryProvider
+	// Admonitions configures the markup of callout blocks. Nil uses the
+	// defaults described on AdmonitionOptions.
+	Admonitions *AdmonitionOptions
+	// DescriptionLength caps descriptions generated, from the first
+	// paragraphs, for pages whose frontmatter has none. Defaults to 160;
+	// negative disables them.
+	DescriptionLength int
+	// Images enables responsive images (see ImageOptions).
+	Images *ImageOptions
+	// HeadingIDs gives headings id attributes derived from their text
+	// (e.g. "Getting started" becomes id="getting-started"), which TOC
+	// entries link to. Off by default, so existing pages render unchanged.
+	HeadingIDs bool
+	// PageBundles enables page bundles (see bundleIndexName): an index.md in
+	// a directory without an _index.md becomes the page at the directory's
+	// URL, instead of an ordinary page at .../index, and the directory's
+	// other files are served under that URL by BundleAssetsMiddleware.
+	PageBundles bool
+	// TemplateFuncs are available to pages that opt in to templating with
+	// "template: true" frontmatter, alongside the built-in "data"
+	// function (see TemplateData).
+	TemplateFuncs texttemplate.FuncMap
 }
 
 func New(fsys fs.FS) *Instance {
-	return &Instance{
-		FS:               fsys,
-		pageDetailsCache: lru.NewCache[string, *DetailedPage](1_000),
-		sitemapCache:     typed.SyncMap[generateSitemapInput, *generateSitemapInnerData]{},
-		basePageCache:    lru.NewCache[string, *Page](1_000),
+	return NewWithOptions(&Options{FS: fsys})
+}
+
+func NewWithOptions(opts *Options) *Instance {
+	maxConcurrency := opts.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = runtime.NumCPU()
+	}
+
+	inst := &Instance{
+		FS:               opts.FS,
+		opts:             *opts,
+		pageDetailsCache: newCache[string, *DetailedPage](opts.PageDetailsCacheSize),
+		sitemapCache:     newCache[generateSitemapInput, *generateSitemapInnerData](opts.SitemapCacheSize),
+		basePageCache:    newCache[string, *Page](opts.BasePageCacheSize),
+		related:          map[string][]relatedCandidate{},
+		data:             map[string]any{},
+		llms:             map[llmsKey][]byte{},
+		dependents:       map[string]map[string]bool{},
+		parseSem:         make(chan struct{}, maxConcurrency),
+	}
+
+	if !opts.DisableBundle && opts.FS != nil {
+		b, err := loadBundle(opts.FS)
+		if err != nil {
+			fmt.Println("Error loading fsmarkdown bundle, falling back to live parsing: ", err)
+		}
+		inst.bundle = b
 	}
+
+	return inst
+}
+
+// WithHistory returns a new Instance with the same options as inst but
+// taking page history from h, with empty caches.
+func (inst *Instance) WithHistory(h HistoryProvider) *Instance {
+	opts := inst.opts
+	opts.History = h
+	return NewWithOptions(&opts)
+}
+
+// WithFS returns a new Instance with the same options as inst but reading
+// from fsys, with empty caches.
+func (inst *Instance) WithFS(fsys fs.FS) *Instance {
+	opts := inst.opts
+	opts.FS = fsys
+	return NewWithOptions(&opts)
+}
+
+// Invalidate clears every cache (pages, sitemaps, search index, redirect
+// table, related pages, permalinks, llms.txt, data files and the image
+// manifest), e.g. after the content changes.
+func (inst *Instance) Invalidate() {
+	inst.basePageCache.Clear()
+
+	inst.dataMu.Lock()
+	inst.data = map[string]any{}
+	inst.dataMu.Unlock()
+
+	inst.dependentsMu.Lock()
+	inst.dependents = map[string]map[string]bool{}
+	inst.dependentsMu.Unlock()
+
+	inst.imageManifestMu.Lock()
+	inst.imageManifest = nil
+	inst.imageManifestMu.Unlock()
+
+	inst.invalidateDerived()
+}
+
+// invalidateDerived clears the caches built from many pages at once.
+func (inst *Instance) invalidateDerived() {
+	inst.pageDetailsCache.Clear()
+	inst.sitemapCache.Clear()
+
+	inst.searchIndexMu.Lock()
+	inst.searchIndex = nil
+	inst.searchIndexMu.Unlock()
+
+	inst.redirectsMu.Lock()
+	inst.redirects, inst.redirectsErr = nil, nil
+	inst.redirectsMu.Unlock()
+
+	inst.relatedMu.Lock()
+	inst.related = map[string][]relatedCandidate{}
+	inst.relatedMu.Unlock()
+
+	inst.permalinkIndexMu.Lock()
+	inst.permalinkIndex = nil
+	inst.permalinkIndexMu.Unlock()
+
+	inst.bundleDirsMu.Lock()
+	inst.bundleDirs, inst.bundleDirsErr = nil, nil
+	inst.bundleDirsMu.Unlock()
+
+	inst.llmsMu.Lock()
+	inst.llms = map[llmsKey][]byte{}
+	inst.llmsMu.Unlock()
 }
 
 type Page struct {
-	Title       string `yaml:"title"`
-	Description string `yaml:"description"`
-	Date        string `yaml:"date"`
-	Content     template.HTML
-	URL         string
-	IsFolder    bool
+	Title       string        `yaml:"title" json:"title"`
+	Description string        `yaml:"description" json:"description,omitempty"`
+	Date        string        `yaml:"date" json:"date,omitempty"`
+	Aliases     []string      `yaml:"aliases" json:"aliases,omitempty"`
+	Tags        []string      `yaml:"tags" json:"tags,omitempty"`
+	Image       string        `yaml:"image" json:"image,omitempty"`
+	PageSize    int           `yaml:"page_size" json:"pageSize,omitempty"`
+	Slug        string        `yaml:"slug" json:"slug,omitempty"`
+	Template    bool          `yaml:"template" json:"-"`
+	Content     template.HTML `yaml:"-" json:"content,omitempty"`
+	URL         string        `yaml:"-" json:"url"`
+	SourcePath  string        `yaml:"-" json:"-"`
+	IsFolder    bool          `yaml:"-" json:"isFolder,omitempty"`
+	FilePath    string        `yaml:"-" json:"-"`
+	TOC         []TOCItem     `yaml:"-" json:"toc,omitempty"`
+	PlainText   string        `yaml:"-" json:"plainText,omitempty"`
+	DependsOn   []string      `yaml:"-" json:"-"` // included and data files
+
+	// LastModified (RFC 3339) and Authors come from Options.History.
+	LastModified string   `yaml:"-" json:"lastModified,omitempty"`
+	Authors      []string `yaml:"-" json:"authors,omitempty"`
+
+	searchTerms []string
+	images      []string
 }
 
 type DetailedPage struct {
@@ -50,6 +255,7 @@ type DetailedPage struct {
 	Sitemap      Sitemap
 	IndexSitemap Sitemap
 	BackItem     string
+	Pagination   *Pagination
 }
 
 type SitemapItem struct {
@@ -60,26 +266,81 @@ type SitemapItem struct {
 
 type Sitemap []SitemapItem
 
+// GetPageDetails returns the page for the request path. Paginated folder
+// listings accept either a "/page/N" path suffix or a "?page=N" query.
 func (inst *Instance) GetPageDetails(r *http.Request) (detailedPage *DetailedPage, err error) {
-	cleanPath := filepath.Clean(r.URL.Path)
+	pageNum, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	return inst.getPageDetails(r.Context(), filepath.Clean(r.URL.Path), max(pageNum, 0))
+}
+
+// GetPageDetailsCtx is like GetPageDetails, for a URL path. Work is
+// abandoned when ctx is done, and concurrent calls for the same uncached
+// page share a single parse.
+func (inst *Instance) GetPageDetailsCtx(ctx context.Context, urlPath string) (*DetailedPage, error) {
+	return inst.getPageDetails(ctx, filepath.Clean(urlPath), 0)
+}
+
+func (inst *Instance) getPageDetails(ctx context.Context, cleanPath string, pageNum int) (*DetailedPage, error) {
+	base, n := cleanPath, pageNum
+	if b, pn, ok := splitPagePath(cleanPath); ok {
+		base, n = b, pn
+	}
+
+	var pageSize int
+	if n > 0 {
+		var err error
+		if pageSize, err = inst.sectionPageSize(ctx, base); err != nil {
+			return nil, err
+		}
+	}
+	if pageSize > 0 {
+		cleanPath, pageNum = base, n
+	} else {
+		// Not a paginated listing, so the path is taken literally
+		pageNum = 0
+	}
 
-	if p, ok := inst.pageDetailsCache.Get(cleanPath); ok {
+	key := pageURL(cleanPath, pageNum)
+
+	if p, ok := inst.pageDetailsCache.Get(key); ok {
 		return p, nil
 	}
 
-	pageBase, found, err := inst.getPageBase(cleanPath)
+	return doShared(ctx, &inst.pageDetailsGroup, key, func(ctx context.Context) (*DetailedPage, error) {
+		return inst.loadPageDetails(ctx, cleanPath, pageNum, key)
+	})
+}
+
+func (inst *Instance) loadPageDetails(ctx context.Context, urlPath string, pageNum int, key string) (*DetailedPage, error) {
+	cleanPath := urlPath
+	pageBase, found, err := inst.resolvePage(ctx, cleanPath)
 	if err != nil {
 		fmt.Println("Error getting pageBase in getPageDetails: ", err)
 		return nil, err
 	}
+	if found {
+		cleanPath = pageBase.SourcePath
+	}
+
+	if !found {
+		ap, ok, err := inst.archivePage(ctx, cleanPath)
+		if err != nil {
+			fmt.Println("Error building archive page in getPageDetails: ", err)
+			return nil, err
+		}
+		if ok {
+			inst.pageDetailsCache.Set(key, ap, false)
+			return ap, nil
+		}
+	}
 
-	var eg errgroup.Group
+	eg, egCtx := errgroup.WithContext(ctx)
 	var indexSitemap, sitemap Sitemap
 	var backItem string
 
 	if pageBase.IsFolder && cleanPath != "/" {
 		eg.Go(func() error {
-			sm, err := inst.generateSitemap(generateSitemapInput{CleanPath: cleanPath, IsIndex: true})
+			sm, err := inst.generateSitemap(egCtx, generateSitemapInput{CleanPath: cleanPath, IsIndex: true})
 			if err != nil {
 				fmt.Println("Error generating sitemap in getPageDetails: ", err)
 				return err
@@ -90,7 +351,7 @@ func (inst *Instance) GetPageDetails(r *http.Request) (detailedPage *DetailedPag
 	}
 
 	eg.Go(func() error {
-		sm, err := inst.generateSitemap(generateSitemapInput{CleanPath: cleanPath, IsIndex: false})
+		sm, err := inst.generateSitemap(egCtx, generateSitemapInput{CleanPath: cleanPath, IsIndex: false})
 		if err != nil {
 			fmt.Println("Error generating sitemap in getPageDetails: ", err)
 			return err
@@ -114,7 +375,17 @@ func (inst *Instance) GetPageDetails(r *http.Request) (detailedPage *DetailedPag
 		BackItem:     backItem,
 	}
 
-	inst.pageDetailsCache.Set(cleanPath, p, !found)
+	if found && pageBase.IsFolder {
+		pageSize, err := inst.sectionPageSize(ctx, cleanPath)
+		if err != nil {
+			return nil, err
+		}
+		if pageSize > 0 && !paginate(p, cleanPath, pageNum, pageSize) {
+			p.Page, p.IndexSitemap, found = notFoundPage, nil, false
+		}
+	}
+
+	inst.pageDetailsCache.Set(key, p, !found)
 
 	return p, nil
 }
@@ -135,10 +406,10 @@ type generateSitemapInnerData struct {
 	DirToUse string
 }
 
-func (inst *Instance) generateSitemap(input generateSitemapInput) (*generateSitemapOutput, error) {
+func (inst *Instance) generateSitemap(ctx context.Context, input generateSitemapInput) (*generateSitemapOutput, error) {
 	var innerData *generateSitemapInnerData
 
-	if x, ok := inst.sitemapCache.Load(input); ok {
+	if x, ok := inst.sitemapCache.Get(input); ok {
 		innerData = x
 	} else {
 		dirToUse := filepath.Dir(input.CleanPath)
@@ -146,22 +417,12 @@ func (inst *Instance) generateSitemap(input generateSitemapInput) (*generateSite
 			dirToUse = "/" + input.CleanPath
 		}
 
-		directChildren, err := fs.ReadDir(inst.FS, filepath.Join("markdown", dirToUse))
-		if err != nil {
-			fmt.Println("Error reading dir in generateSitemap: ", err)
-			return nil, err
-		}
-
-		pages, hasIndex, err := inst.processDirectChildren(directChildren, dirToUse)
-		if err != nil {
-			fmt.Println("Error processing direct children in generateSitemap: ", err)
+		pages, hasIndex, err := inst.listSection(ctx, dirToUse)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Println("Error listing section in generateSitemap: ", err)
 			return nil, err
 		}
-
-		// Sort pages by date
-		sort.Slice(pages, func(i, j int) bool {
-			return pages[i].Date > pages[j].Date
-		})
+		// A missing directory (e.g. for a not-found "/a/b/c") has nothing to list
 
 		var backItem string
 		if !input.IsIndex && hasIndex && input.CleanPath != "/" {
@@ -174,7 +435,7 @@ func (inst *Instance) generateSitemap(input generateSitemapInput) (*generateSite
 			DirToUse: dirToUse,
 		}
 
-		inst.sitemapCache.Store(input, innerData)
+		inst.sitemapCache.Set(input, innerData, false)
 	}
 
 	sitemap := Sitemap{}
@@ -183,7 +444,7 @@ func (inst *Instance) generateSitemap(input generateSitemapInput) (*generateSite
 		sitemap = append(sitemap, item)
 	}
 	for _, p := range innerData.Pages {
-		item := SitemapItem{Title: p.Title, URL: p.URL, IsActive: p.URL == input.CleanPath}
+		item := SitemapItem{Title: p.Title, URL: p.URL, IsActive: p.SourcePath == input.CleanPath}
 		sitemap = append(sitemap, item)
 	}
 
@@ -195,7 +456,35 @@ func (inst *Instance) generateSitemap(input generateSitemapInput) (*generateSite
 	return output, nil
 }
 
-func (inst *Instance) processDirectChildren(directChildren []fs.DirEntry, dirToUse string) ([]*Page, bool, error) {
+// listSection returns the pages directly inside dir (a clean URL path),
+// sorted newest first, and whether dir has an _index.md.
+func (inst *Instance) listSection(ctx context.Context, dir string) ([]*Page, bool, error) {
+	dir = path.Clean("/" + dir)
+
+	if inst.bundle != nil {
+		inst.resolveBundleImageURLs()
+		return inst.bundle.listSection(dir)
+	}
+
+	directChildren, err := fs.ReadDir(inst.FS, filepath.Join("markdown", dir))
+	if err != nil {
+		return nil, false, err
+	}
+
+	pages, hasIndex, err := inst.processDirectChildren(ctx, directChildren, dir)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Sort pages by date
+	sort.SliceStable(pages, func(i, j int) bool {
+		return pages[i].effectiveDate().After(pages[j].effectiveDate())
+	})
+
+	return pages, hasIndex, nil
+}
+
+func (inst *Instance) processDirectChildren(ctx context.Context, directChildren []fs.DirEntry, dirToUse string) ([]*Page, bool, error) {
 	type result struct {
 		index int
 		page  *Page
@@ -204,51 +493,43 @@ func (inst *Instance) processDirectChildren(directChildren []fs.DirEntry, dirToU
 	hasIndex := false
 	results := make([]result, 0, len(directChildren))
 	var mu sync.Mutex
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(directChildren))
 
-	for i, file := range directChildren {
-		wg.Add(1)
-		go func(i int, file fs.DirEntry) {
-			defer wg.Done()
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(cap(inst.parseSem))
 
+	for i, file := range directChildren {
+		eg.Go(func() error {
 			name := strings.TrimSuffix(file.Name(), ".md")
 			if file.Type().IsRegular() && !strings.HasSuffix(file.Name(), ".md") {
-				return
+				return nil
+			}
+			if isPartial(path.Join("markdown", dirToUse, file.Name())) {
+				return nil
 			}
 			if name == "_index" {
 				mu.Lock()
 				hasIndex = true
 				mu.Unlock()
-				return
+				return nil
 			}
 
-			pageBase, found, err := inst.getPageBase(filepath.Join(dirToUse, name))
+			pageBase, found, err := inst.getListedPage(ctx, filepath.Join(dirToUse, name))
 			if err != nil {
-				errChan <- err
-				return
+				return err
 			}
 			if !found {
-				return
-			}
-			if pageBase.Title == "" {
-				pageBase.Title = name
+				return nil
 			}
 
 			mu.Lock()
 			results = append(results, result{index: i, page: pageBase})
 			mu.Unlock()
-		}(i, file)
+			return nil
+		})
 	}
 
-	wg.Wait()
-	close(errChan)
-
-	// Check for errors
-	for err := range errChan {
-		if err != nil {
-			return nil, false, err
-		}
+	if err := eg.Wait(); err != nil {
+		return nil, false, err
 	}
 
 	// Sort results to preserve original order
@@ -270,59 +551,221 @@ var notFoundPage = &Page{
 	Content: "# 404\n\nNothing found.",
 }
 
-func (inst *Instance) getPageBase(cleanPath string) (p *Page, found bool, err error) {
+// IsNotFound reports whether p is the placeholder page returned for
+// paths with no markdown file.
+func (p *Page) IsNotFound() bool {
+	return p == notFoundPage
+}
+
+func (inst *Instance) getPageBase(ctx context.Context, cleanPath string) (p *Page, found bool, err error) {
 	var ok bool
 	if p, ok = inst.basePageCache.Get(cleanPath); ok {
 		return p, true, nil
 	}
 
-	isFolder, fileBytes, err := inst.readPageFile(cleanPath)
+	if inst.bundle != nil {
+		inst.resolveBundleImageURLs()
+		if p, ok = inst.bundle.Pages[cleanPath]; ok {
+			return p, true, nil
+		}
+		return notFoundPage, false, nil
+	}
+
+	p, err = doShared(ctx, &inst.pageBaseGroup, cleanPath, func(ctx context.Context) (*Page, error) {
+		return inst.loadPageBase(ctx, cleanPath)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return p, p != notFoundPage, nil
+}
+
+// getListedPage is getPageBase for listings and corpus-wide indexes: a
+// page that fails to load (e.g. an unknown shortcode or a template error)
+// is logged and skipped as if missing, so that it doesn't break its
+// neighbours. Lint and Validate report such pages. With StrictFrontmatter,
+// or once ctx is done, errors are returned as usual.
+func (inst *Instance) getListedPage(ctx context.Context, cleanPath string) (*Page, bool, error) {
+	p, found, err := inst.getPageBase(ctx, cleanPath)
+	if err == nil || inst.opts.StrictFrontmatter || ctx.Err() != nil {
+		return p, found, err
+	}
+	fmt.Println("Skipping page that failed to load: ", cleanPath, err)
+	return nil, false, nil
+}
+
+func (inst *Instance) loadPageBase(ctx context.Context, cleanPath string) (*Page, error) {
+	select {
+	case inst.parseSem <- struct{}{}:
+		defer func() { <-inst.parseSem }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	filePath, isFolder, fileBytes, err := inst.readPageFile(cleanPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			fmt.Println("Page not found: ", cleanPath, err)
-			return notFoundPage, false, nil
+			return notFoundPage, nil
 		}
-		return nil, false, err
+		return nil, err
 	}
 
-	p, err = inst.parseMarkdown(fileBytes, cleanPath, isFolder)
+	p, err := inst.parseMarkdown(fileBytes, cleanPath, filePath, isFolder)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
-	found = p != notFoundPage
-	inst.basePageCache.Set(cleanPath, p, !found)
-	return p, found, nil
+	inst.basePageCache.Set(cleanPath, p, p == notFoundPage)
+	return p, nil
+}
+
+// doShared runs fn once for concurrent callers with the same key. Each
+// caller stops waiting when its own ctx is done. If the caller whose ctx fn
+// ran with was cancelled, callers that are still live share a fresh run.
+func doShared[T any](ctx context.Context, g *singleflight.Group, key string, fn func(context.Context) (T, error)) (T, error) {
+	var zero T
+	for {
+		var led bool
+		ch := g.DoChan(key, func() (any, error) {
+			led = true
+			return fn(ctx)
+		})
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case res := <-ch:
+			if res.Err != nil {
+				if !led && ctx.Err() == nil && (errors.Is(res.Err, context.Canceled) || errors.Is(res.Err, context.DeadlineExceeded)) {
+					continue
+				}
+				return zero, res.Err
+			}
+			return res.Val.(T), nil
+		}
+	}
 }
 
-func (inst *Instance) readPageFile(cleanPath string) (bool, []byte, error) {
-	fileBytes, err := fs.ReadFile(inst.FS, "markdown"+cleanPath+".md")
+func (inst *Instance) readPageFile(cleanPath string) (string, bool, []byte, error) {
+	if isPartial("markdown" + cleanPath) {
+		return "", false, nil, &fs.PathError{Op: "open", Path: "markdown" + cleanPath + ".md", Err: fs.ErrNotExist}
+	}
+
+	filePath := "markdown" + cleanPath + ".md"
+	if inst.isBundleIndex(filePath) {
+		return "", false, nil, &fs.PathError{Op: "open", Path: filePath, Err: fs.ErrNotExist}
+	}
+	fileBytes, err := fs.ReadFile(inst.FS, filePath)
 	if err == nil {
-		return false, fileBytes, nil
+		return filePath, false, fileBytes, nil
 	}
 
 	if !os.IsNotExist(err) {
-		return false, nil, err
+		return "", false, nil, err
+	}
+
+	filePath = "markdown" + filepath.Join(cleanPath, "_index.md")
+	fileBytes, err = fs.ReadFile(inst.FS, filePath)
+	if err == nil {
+		return filePath, true, fileBytes, nil
 	}
 
-	fileBytes, err = fs.ReadFile(inst.FS, "markdown"+filepath.Join(cleanPath, "_index.md"))
+	if !os.IsNotExist(err) || cleanPath == "/" || !inst.opts.PageBundles {
+		return "", false, nil, err
+	}
+
+	filePath = "markdown" + filepath.Join(cleanPath, bundleIndexName)
+	fileBytes, err = fs.ReadFile(inst.FS, filePath)
 	if err != nil {
-		return false, nil, err
+		return "", false, nil, err
 	}
 
-	return true, fileBytes, nil
+	return filePath, false, fileBytes, nil
 }
 
-func (inst *Instance) parseMarkdown(fileBytes []byte, cleanPath string, isFolder bool) (*Page, error) {
+func (inst *Instance) parseMarkdown(fileBytes []byte, cleanPath, filePath string, isFolder bool) (*Page, error) {
 	var p Page
 	rest, err := frontmatter.Parse(bytes.NewReader(fileBytes), &p)
 	if err != nil {
+		return nil, toFrontmatterParseError(filePath, err)
+	}
+
+	if errs := inst.validateFrontmatter(fileBytes, cleanPath, filePath); len(errs) > 0 {
+		if inst.opts.StrictFrontmatter {
+			return nil, errors.Join(errs...)
+		}
+		for _, err := range errs {
+			fmt.Println("Invalid frontmatter: ", err)
+		}
+	}
+
+	if err := inst.renderPage(&p, rest, fileBytes, cleanPath, filePath, isFolder); err != nil {
 		return nil, err
 	}
 
-	p.Content = template.HTML(blackfriday.Run(rest))
-	p.URL = cleanPath
+	return &p, nil
+}
+
+// checkRender reports the error, if any, that rendering the page would
+// fail with, not counting frontmatter problems.
+func (inst *Instance) checkRender(fileBytes []byte, cleanPath, filePath string, isFolder bool) error {
+	var p Page
+	rest, err := frontmatter.Parse(bytes.NewReader(fileBytes), &p)
+	if err != nil {
+		return nil
+	}
+	return inst.renderPage(&p, rest, fileBytes, cleanPath, filePath, isFolder)
+}
+
+// renderPage fills in p, whose frontmatter is already decoded, from the
+// markdown body rest.
+func (inst *Instance) renderPage(p *Page, rest, fileBytes []byte, cleanPath, filePath string, isFolder bool) error {
+	p.SourcePath = cleanPath
 	p.IsFolder = isFolder
+	p.FilePath = filePath
+	p.URL = inst.permalinkFor(p)
+	// Untitled pages are listed under their file (or directory) name
+	if p.Title == "" && cleanPath != "/" {
+		p.Title = path.Base(cleanPath)
+	}
 
-	return &p, nil
+	if inst.opts.History != nil {
+		if fh, ok := inst.opts.History.FileHistory(filePath); ok {
+			p.LastModified = fh.LastModified.Format(time.RFC3339)
+			p.Authors = fh.Authors
+		}
+	}
+
+	var includes, dataFiles []string
+	rest, err := inst.expandShortcodes(rest, &shortcodeContext{filePath: filePath, stack: []string{filePath}, includes: &includes, data: &dataFiles})
+	if err != nil {
+		return err
+	}
+	inst.recordIncludes(cleanPath, includes)
+	p.DependsOn = append(includes, dataFiles...)
+
+	if p.Template {
+		if rest, err = inst.executeTemplate(rest, fileBytes, p); err != nil {
+			return err
+		}
+	}
+
+	var bundleURL string
+	if inst.isBundleIndex(filePath) {
+		bundleURL = p.URL
+	}
+	rendered := inst.renderMarkdown(rest, bundleURL)
+	p.Content = template.HTML(rendered.html)
+	p.TOC = rendered.toc
+	p.PlainText = rendered.text
+	p.searchTerms = tokenize(p.Title + "\n" + rendered.text)
+	if p.Description == "" && inst.opts.DescriptionLength >= 0 {
+		p.Description = summarize(rendered.prose, cmp.Or(inst.opts.DescriptionLength, defaultDescriptionLength))
+	}
+	p.images = rendered.images
+	slices.Sort(p.DependsOn)
+	p.DependsOn = slices.Compact(p.DependsOn)
+
+	return nil
 }
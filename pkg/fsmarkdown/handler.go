@@ -0,0 +1,143 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"time"
+)
+
+type HandlerOptions struct {
+	// Name of the template to execute. If empty, the template itself is executed.
+	TemplateName string
+	// Cache-Control header for found pages. Defaults to "no-cache", which lets
+	// clients store the page but forces revalidation via ETag / Last-Modified.
+	CacheControl string
+}
+
+// Handler renders the DetailedPage for each request path with tmpl. Missing
+// pages are rendered with a 404 status. Found pages get an ETag (derived from
+// the rendered output) and, when known, a Last-Modified header covering the
+// page and what it is built from (see lastModified), and conditional GETs
+// are answered with 304s.
+func (inst *Instance) Handler(tmpl *template.Template, opts *HandlerOptions) http.Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+
+	cacheControl := opts.CacheControl
+	if cacheControl == "" {
+		cacheControl = "no-cache"
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dp, err := inst.GetPageDetails(r)
+		if err != nil {
+			fmt.Println("Error getting page details in Handler: ", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		var buf bytes.Buffer
+		if opts.TemplateName != "" {
+			err = tmpl.ExecuteTemplate(&buf, opts.TemplateName, dp)
+		} else {
+			err = tmpl.Execute(&buf, dp)
+		}
+		if err != nil {
+			fmt.Println("Error executing template in Handler: ", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+		if dp.IsNotFound() {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(buf.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(buf.Bytes())
+		w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+		w.Header().Set("Cache-Control", cacheControl)
+
+		http.ServeContent(w, r, "", inst.lastModified(r.Context(), dp), bytes.NewReader(buf.Bytes()))
+	})
+}
+
+// lastModified returns when dp's output last changed, as far as is known:
+// the latest time of the page's own file, the files it includes or reads
+// data from (see Page.DependsOn), the pages in its listings and, without
+// Options.History, its directory. File times come from Options.History if
+// set, otherwise from mod times. If any of them is unknown (e.g. mod times
+// are zero on embedded filesystems), it returns zero, leaving Last-Modified
+// unset so that clients revalidate by ETag alone. The frontmatter date is a
+// publish date, so edits wouldn't move it forward.
+func (inst *Instance) lastModified(ctx context.Context, dp *DetailedPage) time.Time {
+	latest, ok := inst.pageTime(dp.Page)
+	if !ok {
+		return time.Time{}
+	}
+	later := func(t time.Time, ok bool) bool {
+		if ok && t.After(latest) {
+			latest = t
+		}
+		return ok
+	}
+
+	deps := dp.DependsOn
+	if inst.opts.History == nil {
+		// The directory's mod time moves when pages are added to or removed
+		// from the listings
+		deps = append([]string{path.Dir(dp.FilePath)}, deps...)
+	}
+	for _, filePath := range deps {
+		if !later(inst.fileTime(filePath)) {
+			return time.Time{}
+		}
+	}
+
+	for _, item := range slices.Concat(dp.Sitemap, dp.IndexSitemap) {
+		p, found, err := inst.resolvePage(ctx, item.URL)
+		if err != nil || !found {
+			continue
+		}
+		if !later(inst.pageTime(p)) {
+			return time.Time{}
+		}
+	}
+
+	return latest
+}
+
+func (inst *Instance) pageTime(p *Page) (time.Time, bool) {
+	if t, ok := parseDate(p.LastModified); ok {
+		return t, true
+	}
+	if p.FilePath == "" {
+		return time.Time{}, false
+	}
+	return inst.fileTime(p.FilePath)
+}
+
+func (inst *Instance) fileTime(filePath string) (time.Time, bool) {
+	if h := inst.opts.History; h != nil {
+		if fh, ok := h.FileHistory(filePath); ok {
+			return fh.LastModified, true
+		}
+	}
+	info, err := fs.Stat(inst.FS, filePath)
+	if err != nil || info.ModTime().IsZero() {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
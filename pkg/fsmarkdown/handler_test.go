@@ -0,0 +1,79 @@
+package fsmarkdown
+
+import (
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestHandlerLastModified(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) time.Time { return base.AddDate(0, 0, days) }
+	file := func(data string, days int) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(data), ModTime: at(days)}
+	}
+	dir := func(days int) *fstest.MapFile {
+		return &fstest.MapFile{Mode: fs.ModeDir | 0755, ModTime: at(days)}
+	}
+	corpus := func() fstest.MapFS {
+		return fstest.MapFS{
+			"markdown":                   dir(0),
+			"markdown/docs":              dir(0),
+			"markdown/_includes":         dir(0),
+			"data":                       dir(0),
+			"markdown/_index.md":         file("---\ntitle: Home\n---\n", 0),
+			"markdown/docs/_index.md":    file("---\ntitle: Docs\n---\n", 0),
+			"markdown/docs/plain.md":     file("---\ntitle: Plain\n---\nHi", 1),
+			"markdown/docs/inc.md":       file("---\ntitle: Inc\n---\n{{< include \"/_includes/frag.md\" >}}", 1),
+			"markdown/_includes/frag.md": file("Fragment", 1),
+			"markdown/docs/data.md":      file("---\ntitle: Data\n---\n{{< data \"site.name\" >}}", 1),
+			"data/site.yaml":             file("name: Site", 1),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		modify func(fstest.MapFS)
+		want   time.Time // zero for no header
+	}{
+		{name: "own file", path: "/docs/plain", want: at(1)},
+		{name: "newer include", path: "/docs/inc", modify: func(m fstest.MapFS) { m["markdown/_includes/frag.md"].ModTime = at(5) }, want: at(5)},
+		{name: "newer data file", path: "/docs/data", modify: func(m fstest.MapFS) { m["data/site.yaml"].ModTime = at(6) }, want: at(6)},
+		{name: "newer sibling", path: "/docs/plain", modify: func(m fstest.MapFS) { m["markdown/docs/inc.md"].ModTime = at(7) }, want: at(7)},
+		{name: "page added to listing", path: "/docs", modify: func(m fstest.MapFS) { m["markdown/docs"].ModTime = at(8) }, want: at(8)},
+		{name: "unknown include time", path: "/docs/inc", modify: func(m fstest.MapFS) { m["markdown/_includes/frag.md"].ModTime = time.Time{} }},
+		{name: "unknown own time", path: "/docs/plain", modify: func(m fstest.MapFS) { m["markdown/docs/plain.md"].ModTime = time.Time{} }},
+	}
+
+	tmpl := template.Must(template.New("").Parse("{{ .Content }}"))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := corpus()
+			if tt.modify != nil {
+				tt.modify(fsys)
+			}
+			rec := httptest.NewRecorder()
+			New(fsys).Handler(tmpl, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d", rec.Code)
+			}
+
+			got := rec.Header().Get("Last-Modified")
+			want := ""
+			if !tt.want.IsZero() {
+				want = tt.want.Format(http.TimeFormat)
+			}
+			if got != want {
+				t.Errorf("Last-Modified = %q, want %q", got, want)
+			}
+			if rec.Header().Get("ETag") == "" {
+				t.Error("no ETag")
+			}
+		})
+	}
+}
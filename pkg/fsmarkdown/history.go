@@ -0,0 +1,100 @@
+package fsmarkdown
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileHistory is the revision history of a single content file.
+type FileHistory struct {
+	LastModified time.Time
+	Authors      []string // most recent contributor first
+}
+
+// HistoryProvider supplies per-file history for pages, keyed by the file's
+// path within Instance.FS (e.g. "markdown/blog/first.md"). See
+// Options.History.
+type HistoryProvider interface {
+	FileHistory(filePath string) (FileHistory, bool)
+}
+
+type gitHistory map[string]FileHistory
+
+func (h gitHistory) FileHistory(filePath string) (FileHistory, bool) {
+	fh, ok := h[filePath]
+	return fh, ok
+}
+
+// NewGitHistory runs git log once over the "markdown" directory of dir (the
+// OS directory that Instance.FS is read from, e.g. "static/private") and
+// returns a provider with each file's last authored commit time and
+// contributors. It is meant to be called at build time, since production
+// servers usually have no git checkout. Renames are not followed, and
+// shallow clones report shallow history.
+func NewGitHistory(dir string) (HistoryProvider, error) {
+	cmd := exec.Command("git", "-C", dir, "log", "--relative", "--name-only", "--format=%x00%at%x00%aN", "--", "markdown")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("fsmarkdown: running git log in %s: %w: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+
+	h := gitHistory{}
+	var commitTime time.Time
+	var author string
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		// Commit header: "\x00<unix time>\x00<author>"
+		if strings.HasPrefix(line, "\x00") {
+			parts := strings.SplitN(line[1:], "\x00", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("fsmarkdown: unexpected git log line %q", line)
+			}
+			secs, err := strconv.ParseInt(parts[0], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("fsmarkdown: unexpected git log line %q: %w", line, err)
+			}
+			commitTime, author = time.Unix(secs, 0).UTC(), parts[1]
+			continue
+		}
+
+		// Commits are listed newest first, so the first one seen for a
+		// file is its last modification.
+		fh, seen := h[line]
+		if !seen {
+			fh.LastModified = commitTime
+		}
+		if !slices.Contains(fh.Authors, author) {
+			fh.Authors = append(fh.Authors, author)
+		}
+		h[line] = fh
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// effectiveDate is the date a page is sorted by: its frontmatter date, or
+// else the last-modified time from its history.
+func (p *Page) effectiveDate() time.Time {
+	if t, ok := parseDate(p.Date); ok {
+		return t
+	}
+	t, _ := parseDate(p.LastModified)
+	return t
+}
@@ -0,0 +1,372 @@
+package fsmarkdown
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// ImageManifestFileName is the file, at the root of Instance.FS, that
+	// records the variants written by WriteImageVariants.
+	ImageManifestFileName = "fsmarkdown_images.json"
+	// ImageVariantsDir is the directory, under the public static dir, that
+	// WriteImageVariants writes resized images to.
+	ImageVariantsDir = "fsmarkdown-images"
+)
+
+// ImageOptions enables responsive images. Markdown images with a
+// root-relative path (e.g. ![Team](/images/team.jpg), relative to the
+// public static dir) render as <img> tags with srcset, sizes, width, height
+// and loading="lazy", using the variants listed in the image manifest.
+// JPEG and PNG images with WebP alternates are wrapped in a <picture> with
+// a <source type="image/webp">. Without a manifest entry (e.g. in dev
+// before a build, or for SVGs), they still get loading="lazy". Remote
+// images are rendered as usual.
+type ImageOptions struct {
+	// Widths of the variants to generate. Widths at or above an image's own
+	// width are skipped. Defaults to 480, 960 and 1440.
+	Widths []int
+	// Sizes is the sizes attribute. Defaults to "100vw".
+	Sizes string
+	// JPEGQuality for JPEG variants. Defaults to 82.
+	JPEGQuality int
+	// URL maps a path relative to the public static dir (e.g.
+	// "images/team.jpg") to the URL it is served at, e.g. kiruna's
+	// GetPublicURL for cache-busted URLs (glue sets this, see
+	// SetDefaultImageURL). Defaults to "/" + path.
+	URL func(publicPath string) string
+}
+
+type imageVariant struct {
+	Width int    `json:"width"`
+	Path  string `json:"path"`
+}
+
+type imageInfo struct {
+	Width    int            `json:"width"`
+	Height   int            `json:"height"`
+	Variants []imageVariant `json:"variants,omitempty"` // narrowest first
+	WebP     []imageVariant `json:"webp,omitempty"`     // same widths, plus full size
+}
+
+var defaultImageWidths = []int{480, 960, 1440}
+
+// localImagePath returns the public-dir-relative path of a root-relative
+// image destination, or false for relative, remote or data URLs.
+func localImagePath(dest string) (string, bool) {
+	if !strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "//") || strings.Contains(dest, ":") {
+		return "", false
+	}
+	dest, _, _ = strings.Cut(dest, "?")
+	dest, _, _ = strings.Cut(dest, "#")
+	return strings.TrimPrefix(path.Clean("/"+dest), "/"), true
+}
+
+// imageURLMarker prefixes public paths in bundled pages, which are written
+// before kiruna hashes the public dir. They are resolved when the bundle is
+// first used (see resolveBundleImageURLs).
+const imageURLMarker = "fsmarkdown-image:"
+
+var imageURLMarkerRe = regexp.MustCompile(regexp.QuoteMeta(imageURLMarker) + `[^\s"',]+`)
+
+// SetDefaultImageURL sets ImageOptions.URL to fn unless it is already set.
+// It does nothing if Options.Images is nil, and must be called before inst
+// is used.
+func (inst *Instance) SetDefaultImageURL(fn func(publicPath string) string) {
+	if inst.opts.Images == nil || inst.opts.Images.URL != nil {
+		return
+	}
+	imageOpts := *inst.opts.Images
+	imageOpts.URL = fn
+	inst.opts.Images = &imageOpts
+}
+
+func (inst *Instance) imageURL(publicPath string) string {
+	if inst.deferImageURLs {
+		return imageURLMarker + publicPath
+	}
+	if opts := inst.opts.Images; opts != nil && opts.URL != nil {
+		return opts.URL(publicPath)
+	}
+	return "/" + publicPath
+}
+
+// resolveBundleImageURLs swaps the markers left in bundled pages for real
+// URLs. It runs once per Instance.
+func (inst *Instance) resolveBundleImageURLs() {
+	inst.bundleImagesOnce.Do(func() {
+		for _, p := range inst.bundle.Pages {
+			p.Content = template.HTML(imageURLMarkerRe.ReplaceAllStringFunc(string(p.Content), func(m string) string {
+				return html.EscapeString(inst.imageURL(html.UnescapeString(strings.TrimPrefix(m, imageURLMarker))))
+			}))
+		}
+	})
+}
+
+// renderImage writes an <img> for a local image and reports true, or
+// reports false to let blackfriday render it.
+func (inst *Instance) renderImage(w io.Writer, node *blackfriday.Node) bool {
+	opts := inst.opts.Images
+	if opts == nil {
+		return false
+	}
+	publicPath, ok := localImagePath(string(node.LinkData.Destination))
+	if !ok {
+		return false
+	}
+
+	attr := func(name, value string) {
+		fmt.Fprintf(w, ` %s="%s"`, name, html.EscapeString(value))
+	}
+
+	info := inst.getImageManifest()[publicPath]
+	sizes := opts.Sizes
+	if sizes == "" {
+		sizes = "100vw"
+	}
+	srcset := func(variants []imageVariant) string {
+		var set []string
+		for _, v := range variants {
+			set = append(set, fmt.Sprintf("%s %dw", inst.imageURL(v.Path), v.Width))
+		}
+		return strings.Join(set, ", ")
+	}
+
+	if info != nil && len(info.WebP) > 0 {
+		fmt.Fprint(w, `<picture><source type="image/webp"`)
+		attr("srcset", srcset(info.WebP))
+		attr("sizes", sizes)
+		fmt.Fprint(w, " />")
+	}
+
+	fmt.Fprint(w, "<img")
+	attr("src", inst.imageURL(publicPath))
+
+	if info != nil {
+		if len(info.Variants) > 0 {
+			full := imageVariant{Width: info.Width, Path: publicPath}
+			attr("srcset", srcset(append(slices.Clone(info.Variants), full)))
+			attr("sizes", sizes)
+		}
+		attr("width", fmt.Sprint(info.Width))
+		attr("height", fmt.Sprint(info.Height))
+	}
+
+	attr("alt", nodeText(node))
+	if len(node.LinkData.Title) > 0 {
+		attr("title", string(node.LinkData.Title))
+	}
+	fmt.Fprint(w, ` loading="lazy" decoding="async" />`)
+
+	if info != nil && len(info.WebP) > 0 {
+		fmt.Fprint(w, "</picture>")
+	}
+
+	return true
+}
+
+// getImageManifest returns the image manifest from FS, or an empty one if
+// there is none.
+func (inst *Instance) getImageManifest() map[string]*imageInfo {
+	inst.imageManifestMu.Lock()
+	defer inst.imageManifestMu.Unlock()
+
+	if inst.imageManifest != nil {
+		return inst.imageManifest
+	}
+
+	manifest := map[string]*imageInfo{}
+	fileBytes, err := fs.ReadFile(inst.FS, ImageManifestFileName)
+	if err == nil {
+		err = json.Unmarshal(fileBytes, &manifest)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println("Error reading image manifest: ", err)
+	}
+	inst.imageManifest = manifest
+
+	return manifest
+}
+
+// WriteImageVariants resizes every local image referenced by a page, found
+// under publicDir (the public static dir on disk), to the configured
+// widths, writing the variants under publicDir/ImageVariantsDir so they are
+// hashed along with the rest of the public dir. It writes the manifest
+// (see ImageManifestFileName) to w. Existing variants newer than their
+// source are reused. It does nothing if Options.Images is nil.
+//
+// Variants are re-encoded in their source format: JPEG as JPEG, WebP as
+// (lossless) WebP, and anything else as PNG. JPEG and PNG images also get
+// lossless WebP alternates, at each variant width and at full size.
+func (inst *Instance) WriteImageVariants(publicDir string, w io.Writer) error {
+	imageOpts := inst.opts.Images
+	if imageOpts == nil {
+		return nil
+	}
+
+	opts := inst.opts
+	opts.DisableBundle = true
+	live := NewWithOptions(&opts)
+	ctx := context.Background()
+
+	widths := imageOpts.Widths
+	if len(widths) == 0 {
+		widths = defaultImageWidths
+	}
+	widths = slices.Sorted(slices.Values(widths))
+
+	paths, err := live.pagePaths()
+	if err != nil {
+		return err
+	}
+
+	manifest := map[string]*imageInfo{}
+	for _, cleanPath := range paths {
+		p, found, err := live.getPageBase(ctx, cleanPath)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		for _, publicPath := range p.images {
+			if _, ok := manifest[publicPath]; ok {
+				continue
+			}
+			info, err := writeVariants(publicDir, publicPath, widths, imageOpts.JPEGQuality)
+			if err != nil {
+				fmt.Printf("Skipping image %s referenced by %s: %v\n", publicPath, p.FilePath, err)
+				continue
+			}
+			manifest[publicPath] = info
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(manifest)
+}
+
+func writeVariants(publicDir, publicPath string, widths []int, quality int) (*imageInfo, error) {
+	srcPath := filepath.Join(publicDir, filepath.FromSlash(publicPath))
+	srcStat, err := os.Stat(srcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	src, format, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	info := &imageInfo{Width: bounds.Dx(), Height: bounds.Dy()}
+
+	ext := ".png"
+	switch format {
+	case "jpeg":
+		ext = ".jpg"
+	case "webp":
+		ext = ".webp"
+	}
+	stem := strings.TrimSuffix(publicPath, path.Ext(publicPath))
+	withWebP := format == "jpeg" || format == "png"
+
+	// write encodes src, scaled to width, to the variant file at relPath
+	// unless it is newer than the source.
+	write := func(width int, relPath, format string) error {
+		outPath := filepath.Join(publicDir, filepath.FromSlash(relPath))
+		if outStat, err := os.Stat(outPath); err == nil && outStat.ModTime().After(srcStat.ModTime()) {
+			return nil
+		}
+		img := src
+		if width != info.Width {
+			height := max(info.Height*width/info.Width, 1)
+			dst := image.NewRGBA(image.Rect(0, 0, width, height))
+			draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+			img = dst
+		}
+		return encodeImage(outPath, img, format, quality)
+	}
+
+	for _, width := range widths {
+		if width <= 0 || width >= info.Width {
+			continue
+		}
+		variant := imageVariant{
+			Width: width,
+			Path:  path.Join(ImageVariantsDir, fmt.Sprintf("%s-%dw%s", stem, width, ext)),
+		}
+		info.Variants = append(info.Variants, variant)
+		if err := write(width, variant.Path, format); err != nil {
+			return nil, err
+		}
+	}
+
+	if withWebP {
+		for _, v := range append(slices.Clone(info.Variants), imageVariant{Width: info.Width}) {
+			webp := imageVariant{
+				Width: v.Width,
+				Path:  path.Join(ImageVariantsDir, fmt.Sprintf("%s-%dw.webp", stem, v.Width)),
+			}
+			info.WebP = append(info.WebP, webp)
+			if err := write(webp.Width, webp.Path, "webp"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return info, nil
+}
+
+func encodeImage(outPath string, img image.Image, format string, quality int) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "jpeg":
+		if quality <= 0 {
+			quality = 82
+		}
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
+	case "webp":
+		err = nativewebp.Encode(out, img, nil)
+	default:
+		err = png.Encode(out, img)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "golang.org/x/image/webp"
+)
+
+func TestWriteImageVariants(t *testing.T) {
+	publicDir := t.TempDir()
+	var src bytes.Buffer
+	if err := png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 600, 300))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(publicDir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(publicDir, "images", "team.png"), src.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"markdown/page.md": {Data: []byte("![Team](/images/team.png)\n\n![Rel](team.png)")},
+	}
+	opts := &Options{FS: fsys, Images: &ImageOptions{Widths: []int{240, 480, 960}}}
+
+	var manifest bytes.Buffer
+	if err := NewWithOptions(opts).WriteImageVariants(publicDir, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]*imageInfo
+	if err := json.Unmarshal(manifest.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	info := got["images/team.png"]
+	if info == nil {
+		t.Fatalf("manifest %s has no entry for images/team.png", manifest.Bytes())
+	}
+	if len(info.Variants) != 2 || len(info.WebP) != 3 {
+		t.Fatalf("got %d variants and %d WebP alternates, want 2 and 3", len(info.Variants), len(info.WebP))
+	}
+	for _, v := range info.WebP {
+		f, err := os.Open(filepath.Join(publicDir, filepath.FromSlash(v.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg, format, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || format != "webp" || cfg.Width != v.Width {
+			t.Errorf("%s: decoded %s %dpx (%v), want webp %dpx", v.Path, format, cfg.Width, err, v.Width)
+		}
+	}
+
+	fsys[ImageManifestFileName] = &fstest.MapFile{Data: manifest.Bytes()}
+	p, _, err := NewWithOptions(opts).getPageBase(context.Background(), "/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(p.Content)
+	for _, want := range []string{
+		`<picture><source type="image/webp" srcset="/fsmarkdown-images/images/team-240w.webp 240w, `,
+		`srcset="/fsmarkdown-images/images/team-240w.png 240w, /fsmarkdown-images/images/team-480w.png 480w, /images/team.png 600w"`,
+		`width="600" height="300"`,
+		`loading="lazy" decoding="async" /></picture>`,
+		`src="team.png"`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content %q does not contain %q", content, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/adrg/frontmatter"
+)
+
+// includeShortcode inserts another markdown file, resolved relative to the
+// including file's directory, or to the markdown root if the path starts
+// with "/". The fragment's frontmatter, if any, is dropped and its own
+// shortcodes are expanded. Fragments kept in the includes directory (e.g.
+// markdown/_includes/install-steps.md) are not served as pages themselves.
+func includeShortcode(inst *Instance, sc *shortcodeContext, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("include shortcode needs exactly one path")
+	}
+
+	target, ok := includeTarget(sc.filePath, args[0])
+	if !ok {
+		return "", fmt.Errorf("%s is outside the markdown directory", args[0])
+	}
+
+	if slices.Contains(sc.stack, target) {
+		return "", fmt.Errorf("include cycle: %s -> %s", strings.Join(sc.stack, " -> "), target)
+	}
+
+	fileBytes, err := fs.ReadFile(inst.FS, target)
+	if err != nil {
+		return "", err
+	}
+	*sc.includes = append(*sc.includes, target)
+
+	var discard map[string]any
+	rest, err := frontmatter.Parse(bytes.NewReader(fileBytes), &discard)
+	if err != nil {
+		return "", toFrontmatterParseError(target, err)
+	}
+
+	return inst.expandShortcodesString(rest, &shortcodeContext{
+		filePath: target,
+		stack:    append(slices.Clip(sc.stack), target),
+		includes: sc.includes,
+		data:     sc.data,
+	})
+}
+
+// includeTarget resolves an include shortcode's path argument, as used in
+// filePath, to a path within FS. It reports false if the result is outside
+// the markdown directory.
+func includeTarget(filePath, arg string) (string, bool) {
+	var target string
+	if strings.HasPrefix(arg, "/") {
+		target = path.Join("markdown", arg)
+	} else {
+		target = path.Join(path.Dir(filePath), arg)
+	}
+	return target, strings.HasPrefix(target, "markdown/")
+}
+
+// includeRefs returns the files that the source of filePath includes
+// directly, without rendering it, so it works on pages that fail to parse
+// or render.
+func includeRefs(filePath string, src []byte) []string {
+	var refs []string
+	for _, chunk := range splitFencedCode(src) {
+		if chunk.fenced {
+			continue
+		}
+		for _, m := range shortcodeRe.FindAllSubmatch(chunk.text, -1) {
+			if string(m[1]) != "include" {
+				continue
+			}
+			for _, arg := range shortcodeArgRe.FindAllSubmatch(m[2], -1) {
+				if target, ok := includeTarget(filePath, string(arg[1])); ok {
+					refs = append(refs, target)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+func (inst *Instance) expandShortcodesString(src []byte, sc *shortcodeContext) (string, error) {
+	out, err := inst.expandShortcodes(src, sc)
+	return string(out), err
+}
+
+// includesDir, under the markdown directory, holds include-only fragments
+// (see includeShortcode).
+const includesDir = "markdown/_includes"
+
+// isPartial reports whether filePath (within FS) is in, or is, the includes
+// directory, so is not a page.
+func isPartial(filePath string) bool {
+	return filePath == includesDir || strings.HasPrefix(filePath, includesDir+"/")
+}
+
+// recordIncludes remembers which files the page at cleanPath includes,
+// replacing what was recorded for it before.
+func (inst *Instance) recordIncludes(cleanPath string, includes []string) {
+	inst.dependentsMu.Lock()
+	defer inst.dependentsMu.Unlock()
+
+	for _, pages := range inst.dependents {
+		delete(pages, cleanPath)
+	}
+	for _, filePath := range includes {
+		if inst.dependents[filePath] == nil {
+			inst.dependents[filePath] = map[string]bool{}
+		}
+		inst.dependents[filePath][cleanPath] = true
+	}
+}
+
+// InvalidateFile evicts the cached page for the given markdown file (a path
+// within FS, e.g. "markdown/_includes/install.md") along with every page that
+// includes it, directly or transitively, and resets the corpus-wide caches
+// (sitemaps, search index, redirects, related pages, permalinks and
+// llms.txt). Any other file invalidates everything, as Invalidate does.
+func (inst *Instance) InvalidateFile(filePath string) {
+	filePath = path.Clean(filePath)
+	if !strings.HasPrefix(filePath, "markdown/") || !strings.HasSuffix(filePath, ".md") {
+		inst.Invalidate()
+		return
+	}
+
+	cleanPath, _ := inst.sourcePathForFile(filePath)
+	inst.basePageCache.Delete(cleanPath)
+
+	inst.dependentsMu.Lock()
+	for page := range inst.dependents[filePath] {
+		inst.basePageCache.Delete(page)
+	}
+	inst.dependentsMu.Unlock()
+
+	inst.invalidateDerived()
+}
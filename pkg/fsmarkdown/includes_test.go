@@ -0,0 +1,151 @@
+package fsmarkdown
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIncludeShortcode(t *testing.T) {
+	fragments := map[string]string{
+		"markdown/_includes/frag.md":    "---\ntitle: Fragment\n---\nFragment text",
+		"markdown/_includes/outer.md":   "Outer {{< include \"inner.md\" >}}",
+		"markdown/_includes/inner.md":   "inner text",
+		"markdown/_includes/cycle-a.md": "{{< include \"cycle-b.md\" >}}",
+		"markdown/_includes/cycle-b.md": "{{< include \"cycle-a.md\" >}}",
+		"markdown/docs/sibling.md":      "Sibling text",
+	}
+
+	tests := []struct {
+		name    string
+		page    string
+		want    []string
+		wantNot []string
+		wantErr bool
+	}{
+		{name: "absolute", page: `{{< include "/_includes/frag.md" >}}`, want: []string{"Fragment text"}, wantNot: []string{"title:"}},
+		{name: "relative", page: `{{< include "sibling.md" >}}`, want: []string{"Sibling text"}},
+		{name: "relative parent", page: `{{< include "../_includes/frag.md" >}}`, want: []string{"Fragment text"}},
+		{name: "nested", page: `{{< include "/_includes/outer.md" >}}`, want: []string{"Outer inner text"}},
+		{name: "fenced code is left alone", page: "```\n{{< include \"/_includes/frag.md\" >}}\n```\n", want: []string{"{{&lt; include"}, wantNot: []string{"Fragment text"}},
+		{name: "cycle", page: `{{< include "/_includes/cycle-a.md" >}}`, wantErr: true},
+		{name: "missing", page: `{{< include "/_includes/nope.md" >}}`, wantErr: true},
+		{name: "outside markdown", page: `{{< include "../../secret.md" >}}`, wantErr: true},
+		{name: "no path", page: `{{< include >}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"markdown/docs/page.md": {Data: []byte("---\ntitle: Page\n---\n" + tt.page)}}
+			for name, data := range fragments {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+
+			p, _, err := New(fsys).getPageBase(context.Background(), "/docs/page")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("getPageBase() = nil error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content := string(p.Content)
+			for _, s := range tt.want {
+				if !strings.Contains(content, s) {
+					t.Errorf("content %q does not contain %q", content, s)
+				}
+			}
+			for _, s := range tt.wantNot {
+				if strings.Contains(content, s) {
+					t.Errorf("content %q contains %q", content, s)
+				}
+			}
+		})
+	}
+}
+
+func TestIncludesAreNotServed(t *testing.T) {
+	inst := New(fstest.MapFS{
+		"markdown/_includes/frag.md": {Data: []byte("---\ntitle: Fragment\n---\nFragment text")},
+	})
+	if _, found, err := inst.getPageBase(context.Background(), "/_includes/frag"); err != nil || found {
+		t.Errorf("getPageBase(/_includes/frag) = found %v, %v, want not found", found, err)
+	}
+}
+
+func TestInvalidateFileIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/page.md":            {Data: []byte("---\ntitle: Page\n---\n{{< include \"/_includes/outer.md\" >}}")},
+		"markdown/other.md":           {Data: []byte("---\ntitle: Other\n---\nOther v1")},
+		"markdown/_includes/outer.md": {Data: []byte("{{< include \"inner.md\" >}}")},
+		"markdown/_includes/inner.md": {Data: []byte("Inner v1")},
+	}
+	inst := New(fsys)
+	ctx := context.Background()
+
+	content := func(cleanPath string) string {
+		t.Helper()
+		p, _, err := inst.getPageBase(ctx, cleanPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(p.Content)
+	}
+	content("/page")
+	content("/other")
+
+	fsys["markdown/_includes/inner.md"].Data = []byte("Inner v2")
+	fsys["markdown/other.md"].Data = []byte("---\ntitle: Other\n---\nOther v2")
+	inst.InvalidateFile("markdown/_includes/inner.md")
+
+	if got := content("/page"); !strings.Contains(got, "Inner v2") {
+		t.Errorf("page including the file, transitively = %q, want it reloaded", got)
+	}
+	if got := content("/other"); !strings.Contains(got, "Other v1") {
+		t.Errorf("unrelated page = %q, want it still cached", got)
+	}
+}
+
+func TestLintUnusedInclude(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []string // unused fragments
+	}{
+		{name: "included", page: "---\ntitle: Page\n---\n{{< include \"/_includes/a.md\" >}}", want: []string{"markdown/_includes/c.md"}},
+		{name: "relative", page: "---\ntitle: Page\n---\n{{< include \"_includes/c.md\" >}}", want: []string{"markdown/_includes/a.md", "markdown/_includes/b.md"}},
+		{name: "invalid frontmatter", page: "---\ntitle: [\n---\n{{< include \"/_includes/a.md\" >}}", want: []string{"markdown/_includes/c.md"}},
+		{name: "render error", page: "---\ntitle: Page\n---\n{{< nope >}}\n{{< include \"/_includes/c.md\" >}}", want: []string{"markdown/_includes/a.md", "markdown/_includes/b.md"}},
+		{name: "fenced code", page: "---\ntitle: Page\n---\n```\n{{< include \"/_includes/a.md\" >}}\n```\n", want: []string{"markdown/_includes/a.md", "markdown/_includes/b.md", "markdown/_includes/c.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := New(fstest.MapFS{
+				"markdown/page.md":        {Data: []byte(tt.page)},
+				"markdown/_includes/a.md": {Data: []byte("{{< include \"b.md\" >}}")},
+				"markdown/_includes/b.md": {Data: []byte("B")},
+				"markdown/_includes/c.md": {Data: []byte("C")},
+			})
+			report, err := inst.Lint(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, issue := range report.Issues {
+				if issue.Rule == "unused-include" {
+					got = append(got, issue.File)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("unused includes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
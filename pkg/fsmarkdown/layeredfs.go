@@ -0,0 +1,195 @@
+package fsmarkdown
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+)
+
+// Layer is a named filesystem in a LayeredFS.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+// LayeredFS overlays an ordered stack of filesystems, e.g. base docs
+// shipped in a library overridden by app-specific files. Each file comes
+// from the first layer that has it, and directory listings are merged
+// across layers, so a page in any layer shows up in its section.
+type LayeredFS struct {
+	layers []Layer
+}
+
+// NewLayeredFS returns a LayeredFS over layers, highest precedence first.
+func NewLayeredFS(layers ...Layer) *LayeredFS {
+	return &LayeredFS{layers: layers}
+}
+
+// Layer returns the name of the layer that name is read from.
+func (l *LayeredFS) Layer(name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range l.layers {
+		if _, err := fs.Stat(layer.FS, name); err == nil {
+			return layer.Name, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (l *LayeredFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range l.layers {
+		f, err := layer.FS.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if !info.IsDir() {
+			return f, nil
+		}
+
+		entries, err := l.ReadDir(name)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &layeredDir{File: f, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (l *LayeredFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range l.layers {
+		b, err := fs.ReadFile(layer.FS, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return b, err
+	}
+	return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrNotExist}
+}
+
+func (l *LayeredFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range l.layers {
+		info, err := fs.Stat(layer.FS, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return info, err
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir merges the listings of name in every layer that has it. Where
+// layers share an entry name, the highest-precedence layer's entry wins.
+func (l *LayeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	var found bool
+	byName := map[string]fs.DirEntry{}
+	for _, layer := range l.layers {
+		entries, err := fs.ReadDir(layer.FS, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, entry := range entries {
+			if _, ok := byName[entry.Name()]; !ok {
+				byName[entry.Name()] = entry
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(byName))
+	for _, entry := range byName {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	return entries, nil
+}
+
+// layeredDir is a directory opened from the first layer that has it, with
+// the merged listing.
+type layeredDir struct {
+	fs.File
+	entries []fs.DirEntry
+}
+
+func (d *layeredDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+// PageSource describes where a page was read from.
+type PageSource struct {
+	URL      string `json:"url"`
+	FilePath string `json:"filePath"`
+	// Layer is the name of the LayeredFS layer the file came from, or empty
+	// if Instance.FS isn't a LayeredFS.
+	Layer string `json:"layer,omitempty"`
+}
+
+// PageSource reports which file, and which layer of a LayeredFS, the page
+// at urlPath is read from. Useful for debugging overrides.
+func (inst *Instance) PageSource(urlPath string) (*PageSource, error) {
+	ctx := context.Background()
+
+	p, found, err := inst.resolvePage(ctx, path.Clean("/"+urlPath))
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "open", Path: urlPath, Err: fs.ErrNotExist}
+	}
+
+	src := &PageSource{URL: p.URL, FilePath: p.FilePath}
+	if l, ok := inst.FS.(*LayeredFS); ok {
+		if src.Layer, err = l.Layer(p.FilePath); err != nil {
+			return nil, err
+		}
+	}
+
+	return src, nil
+}
@@ -0,0 +1,268 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/adrg/frontmatter"
+)
+
+const (
+	LintSeverityError   = "error"
+	LintSeverityWarning = "warning"
+)
+
+type LintIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Message  string `json:"message"`
+}
+
+type LintReport struct {
+	Issues []LintIssue `json:"issues"`
+}
+
+type LintOptions struct {
+	// Descriptions longer than this are flagged. Defaults to 160, roughly
+	// what search engines display.
+	MaxDescriptionLength int
+}
+
+// Lint checks the whole markdown corpus for content problems: invalid or
+// schema-violating frontmatter, pages that fail to render, missing titles
+// and descriptions, overly long descriptions, duplicate titles and URLs,
+// unusable slugs and undated pages under date permalink patterns,
+// conflicting aliases, orphan pages (in a directory chain missing an
+// _index.md), fragments in the includes directory that no page includes,
+// and non-.md files that are silently skipped (other than page bundle
+// assets). Caches are bypassed.
+func (inst *Instance) Lint(opts *LintOptions) (*LintReport, error) {
+	if opts == nil {
+		opts = &LintOptions{}
+	}
+	maxDescLen := opts.MaxDescriptionLength
+	if maxDescLen <= 0 {
+		maxDescLen = 160
+	}
+
+	report := &LintReport{Issues: []LintIssue{}}
+	add := func(rule, severity, file string, line int, url, msg string) {
+		report.Issues = append(report.Issues, LintIssue{
+			Rule: rule, Severity: severity, File: file, Line: line, URL: url, Message: msg,
+		})
+	}
+
+	var mdFiles, otherFiles, partials []string
+	hasIndex := map[string]bool{}
+
+	err := fs.WalkDir(inst.FS, "markdown", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(p, ".md") {
+			if d.Type().IsRegular() {
+				otherFiles = append(otherFiles, p)
+			}
+			return nil
+		}
+		if isPartial(p) {
+			partials = append(partials, p)
+			return nil
+		}
+		mdFiles = append(mdFiles, p)
+		if path.Base(p) == "_index.md" {
+			hasIndex[path.Dir(strings.TrimPrefix(p, "markdown"))] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, filePath := range otherFiles {
+		if !inst.inPageBundle(filePath) {
+			add("skipped-file", LintSeverityWarning, filePath, 0, "", "file is not a .md file and is ignored")
+		}
+	}
+
+	filesByURL := map[string][]string{}
+	urlsByTitle := map[string][]string{}
+	urlsByAlias := map[string][]string{}
+
+	for _, filePath := range mdFiles {
+		sourcePath, isFolder := inst.sourcePathForFile(filePath)
+
+		fileBytes, err := fs.ReadFile(inst.FS, filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var p Page
+		if _, err := frontmatter.Parse(bytes.NewReader(fileBytes), &p); err != nil {
+			fmErr := toFrontmatterParseError(filePath, err).(*FrontmatterError)
+			add("frontmatter", LintSeverityError, filePath, fmErr.Line, sourcePath, fmErr.Msg)
+			filesByURL[sourcePath] = append(filesByURL[sourcePath], filePath)
+			continue
+		}
+
+		p.SourcePath, p.IsFolder = sourcePath, isFolder
+		url, problem := inst.permalink(&p)
+		filesByURL[url] = append(filesByURL[url], filePath)
+		if problem != "" {
+			add("permalink", LintSeverityWarning, filePath, 0, url, problem)
+		}
+
+		for _, err := range inst.validateFrontmatter(fileBytes, sourcePath, filePath) {
+			var fmErr *FrontmatterError
+			if errors.As(err, &fmErr) {
+				add("frontmatter", LintSeverityError, filePath, fmErr.Line, url, fmErr.Field+": "+fmErr.Msg)
+			}
+		}
+
+		if err := inst.checkRender(fileBytes, sourcePath, filePath, isFolder); err != nil {
+			add("render", LintSeverityError, filePath, 0, url, strings.TrimPrefix(err.Error(), "fsmarkdown: "+filePath+": "))
+		}
+
+		lines := frontmatterKeyLines(fileBytes)
+
+		for _, alias := range p.Aliases {
+			alias = path.Clean("/" + alias)
+			urlsByAlias[alias] = append(urlsByAlias[alias], url)
+		}
+
+		if strings.TrimSpace(p.Title) == "" {
+			add("missing-title", LintSeverityWarning, filePath, 0, url, "page has no title")
+		} else {
+			urlsByTitle[p.Title] = append(urlsByTitle[p.Title], url)
+		}
+
+		if strings.TrimSpace(p.Description) == "" {
+			add("missing-description", LintSeverityWarning, filePath, 0, url, "page has no description")
+		} else if n := len([]rune(p.Description)); n > maxDescLen {
+			add("long-description", LintSeverityWarning, filePath, lines["description"], url,
+				fmt.Sprintf("description is %d characters (max %d)", n, maxDescLen))
+		}
+
+		for dir := path.Dir(sourcePath); dir != "/" && dir != "."; dir = path.Dir(dir) {
+			if !hasIndex[dir] {
+				add("orphan", LintSeverityWarning, filePath, 0, url,
+					fmt.Sprintf("unreachable from navigation: %s has no _index.md", "markdown"+dir))
+				break
+			}
+		}
+	}
+
+	// Fragments are used if a page includes them, directly or through other
+	// fragments. Sources are scanned rather than rendered, so a page that
+	// fails to parse or render still counts.
+	included := map[string]bool{}
+	queue := slices.Clone(mdFiles)
+	for len(queue) > 0 {
+		filePath := queue[0]
+		queue = queue[1:]
+		src, err := fs.ReadFile(inst.FS, filePath)
+		if err != nil {
+			continue
+		}
+		for _, ref := range includeRefs(filePath, src) {
+			if !included[ref] {
+				included[ref] = true
+				queue = append(queue, ref)
+			}
+		}
+	}
+
+	for _, filePath := range partials {
+		if !included[filePath] {
+			add("unused-include", LintSeverityWarning, filePath, 0, "", "file is in the includes directory but not included by any page")
+		}
+	}
+
+	for url, files := range filesByURL {
+		if len(files) > 1 {
+			add("duplicate-url", LintSeverityError, files[0], 0, url,
+				fmt.Sprintf("URL is produced by multiple files: %s", strings.Join(files, ", ")))
+		}
+	}
+
+	for alias, urls := range urlsByAlias {
+		sort.Strings(urls)
+		urls = slices.DeleteFunc(urls, func(url string) bool { return url == alias })
+		if len(urls) == 0 {
+			continue
+		}
+		if files, ok := filesByURL[alias]; ok {
+			add("alias-conflict", LintSeverityError, filesByURL[urls[0]][0], 0, urls[0],
+				fmt.Sprintf("alias %s conflicts with page %s", alias, files[0]))
+		} else if len(urls) > 1 {
+			add("alias-conflict", LintSeverityError, filesByURL[urls[0]][0], 0, urls[0],
+				fmt.Sprintf("alias %s is claimed by multiple pages: %s", alias, strings.Join(urls, ", ")))
+		}
+	}
+
+	for title, urls := range urlsByTitle {
+		if len(urls) > 1 {
+			sort.Strings(urls)
+			add("duplicate-title", LintSeverityWarning, filesByURL[urls[0]][0], 0, urls[0],
+				fmt.Sprintf("title %q is used by multiple pages: %s", title, strings.Join(urls, ", ")))
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		a, b := report.Issues[i], report.Issues[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Rule < b.Rule
+	})
+
+	return report, nil
+}
+
+func (r *LintReport) HasErrors() bool {
+	for _, issue := range r.Issues {
+		if issue.Severity == LintSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LintReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+func (r *LintReport) Text() string {
+	var sb strings.Builder
+	var errCount, warnCount int
+	for _, issue := range r.Issues {
+		loc := issue.File
+		if issue.Line > 0 {
+			loc += fmt.Sprintf(":%d", issue.Line)
+		}
+		fmt.Fprintf(&sb, "%s: %s [%s] %s\n", loc, issue.Severity, issue.Rule, issue.Message)
+		if issue.Severity == LintSeverityError {
+			errCount++
+		} else {
+			warnCount++
+		}
+	}
+	fmt.Fprintf(&sb, "%d error(s), %d warning(s)\n", errCount, warnCount)
+	return sb.String()
+}
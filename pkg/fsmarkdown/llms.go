@@ -0,0 +1,304 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/adrg/frontmatter"
+	"github.com/sjc5/kit/pkg/response"
+)
+
+// RawMarkdownOptions configures RawMarkdownMiddleware.
+type RawMarkdownOptions struct {
+	// IncludeFrontmatter serves files as-is. By default frontmatter is
+	// stripped.
+	IncludeFrontmatter bool
+}
+
+// LLMsOptions configures the llms.txt files (see https://llmstxt.org).
+type LLMsOptions struct {
+	// SiteName is the title. Defaults to the home page's title, and the
+	// title is left out if neither is set.
+	SiteName string
+	// Summary is the blockquote under the title. Defaults to the home
+	// page's description.
+	Summary string
+	// BaseURL makes links absolute, e.g. "https://example.com".
+	BaseURL string
+}
+
+// markdownURL is where a page's source is served: its URL plus ".md", or
+// /index.md for the home page.
+func markdownURL(pageURL string) string {
+	if pageURL == "/" {
+		return "/index.md"
+	}
+	return pageURL + ".md"
+}
+
+// rawMarkdown returns the source of the page whose markdownURL is urlPath.
+func (inst *Instance) rawMarkdown(ctx context.Context, urlPath string, includeFrontmatter bool) ([]byte, *Page, bool, error) {
+	pageURL := strings.TrimSuffix(path.Clean(urlPath), ".md")
+	if pageURL == "/index" {
+		pageURL = "/"
+	}
+
+	p, found, err := inst.resolvePage(ctx, pageURL)
+	if err != nil || !found || markdownURL(p.URL) != urlPath {
+		return nil, nil, false, err
+	}
+
+	return inst.pageSource(p, includeFrontmatter)
+}
+
+func (inst *Instance) pageSource(p *Page, includeFrontmatter bool) ([]byte, *Page, bool, error) {
+	fileBytes, err := fs.ReadFile(inst.FS, p.FilePath)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if includeFrontmatter {
+		return fileBytes, p, true, nil
+	}
+
+	var discard map[string]any
+	body, err := frontmatter.Parse(bytes.NewReader(fileBytes), &discard)
+	if err != nil {
+		return nil, nil, false, toFrontmatterParseError(p.FilePath, err)
+	}
+	return bytes.TrimLeft(body, "\n"), p, true, nil
+}
+
+// RawMarkdownMiddleware serves each page's markdown source at its URL plus
+// ".md" (/index.md for the home page). Other requests pass through to
+// next.
+func (inst *Instance) RawMarkdownMiddleware(opts *RawMarkdownOptions) func(http.Handler) http.Handler {
+	if opts == nil {
+		opts = &RawMarkdownOptions{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, ".md") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			src, _, found, err := inst.rawMarkdown(r.Context(), r.URL.Path, opts.IncludeFrontmatter)
+			if err != nil {
+				fmt.Println("Error reading markdown source in RawMarkdownMiddleware: ", err)
+				response.New(w).InternalServerError()
+				return
+			}
+			if !found {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Write(src)
+		})
+	}
+}
+
+// LLMsTxt builds an llms.txt index from the nav tree: a title and summary,
+// then a section per folder listing its pages' markdown sources (see
+// RawMarkdownMiddleware) with their descriptions. Top-level pages are
+// listed under "Pages".
+func (inst *Instance) LLMsTxt(ctx context.Context, opts *LLMsOptions) ([]byte, error) {
+	nav, home, err := inst.llmsNav(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	inst.writeLLMsHeader(&buf, home, opts)
+
+	var topLevel []*NavNode
+	for _, child := range nav.Children {
+		if !child.IsFolder {
+			topLevel = append(topLevel, child)
+		}
+	}
+	if len(topLevel) > 0 {
+		buf.WriteString("## Pages\n\n")
+		for _, node := range topLevel {
+			if err := inst.writeLLMsLink(ctx, &buf, node, opts); err != nil {
+				return nil, err
+			}
+		}
+		buf.WriteString("\n")
+	}
+
+	var writeSection func(node *NavNode, level int) error
+	writeSection = func(node *NavNode, level int) error {
+		fmt.Fprintf(&buf, "%s %s\n\n", strings.Repeat("#", min(level, 6)), node.Title)
+		if err := inst.writeLLMsLink(ctx, &buf, node, opts); err != nil {
+			return err
+		}
+		for _, child := range node.Children {
+			if !child.IsFolder {
+				if err := inst.writeLLMsLink(ctx, &buf, child, opts); err != nil {
+					return err
+				}
+			}
+		}
+		buf.WriteString("\n")
+		for _, child := range node.Children {
+			if child.IsFolder {
+				if err := writeSection(child, level+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, child := range nav.Children {
+		if child.IsFolder {
+			if err := writeSection(child, 2); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// LLMsFullTxt is like LLMsTxt, but inlines the markdown source of every
+// page, in nav order, instead of linking to it.
+func (inst *Instance) LLMsFullTxt(ctx context.Context, opts *LLMsOptions) ([]byte, error) {
+	nav, home, err := inst.llmsNav(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	inst.writeLLMsHeader(&buf, home, opts)
+
+	var writePages func(node *NavNode) error
+	writePages = func(node *NavNode) error {
+		p, found, err := inst.navPage(ctx, node)
+		if err != nil {
+			return err
+		}
+		if found {
+			src, _, _, err := inst.pageSource(p, false)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&buf, "---\n\n# %s\n\nSource: %s\n\n", p.Title, strings.TrimSuffix(opts.BaseURL, "/")+p.URL)
+			buf.Write(bytes.TrimSpace(src))
+			buf.WriteString("\n\n")
+		}
+		for _, child := range node.Children {
+			if err := writePages(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, child := range nav.Children {
+		if err := writePages(child); err != nil {
+			return nil, err
+		}
+	}
+
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func (inst *Instance) llmsNav(ctx context.Context) (*NavNode, *Page, error) {
+	nav, err := inst.navTree(ctx, "/")
+	if err != nil {
+		return nil, nil, err
+	}
+	home, found, err := inst.getPageBase(ctx, "/")
+	if err != nil {
+		return nil, nil, err
+	}
+	if !found {
+		home = &Page{}
+	}
+	return nav, home, nil
+}
+
+// writeLLMsHeader writes the title and summary. Without a SiteName or a
+// titled home page, the title is left out.
+func (inst *Instance) writeLLMsHeader(buf *bytes.Buffer, home *Page, opts *LLMsOptions) {
+	title := cmp.Or(opts.SiteName, home.Title)
+	summary := cmp.Or(opts.Summary, home.Description)
+
+	if title != "" {
+		fmt.Fprintf(buf, "# %s\n\n", title)
+	}
+	if summary != "" {
+		fmt.Fprintf(buf, "> %s\n\n", summary)
+	}
+}
+
+func (inst *Instance) writeLLMsLink(ctx context.Context, buf *bytes.Buffer, node *NavNode, opts *LLMsOptions) error {
+	p, found, err := inst.navPage(ctx, node)
+	if err != nil || !found {
+		return err
+	}
+	fmt.Fprintf(buf, "- [%s](%s)", p.Title, strings.TrimSuffix(opts.BaseURL, "/")+markdownURL(p.URL))
+	if p.Description != "" {
+		fmt.Fprintf(buf, ": %s", p.Description)
+	}
+	buf.WriteString("\n")
+	return nil
+}
+
+// navPage returns the page behind a nav node, whose URL may be a
+// permalink.
+func (inst *Instance) navPage(ctx context.Context, node *NavNode) (*Page, bool, error) {
+	return inst.resolvePage(ctx, node.URL)
+}
+
+// LLMsTxtHandler serves LLMsTxt, e.g. at /llms.txt.
+func (inst *Instance) LLMsTxtHandler(opts *LLMsOptions) http.Handler {
+	return inst.llmsHandler(opts, false, inst.LLMsTxt)
+}
+
+// LLMsFullTxtHandler serves LLMsFullTxt, e.g. at /llms-full.txt.
+func (inst *Instance) LLMsFullTxtHandler(opts *LLMsOptions) http.Handler {
+	return inst.llmsHandler(opts, true, inst.LLMsFullTxt)
+}
+
+type llmsKey struct {
+	full bool
+	opts LLMsOptions
+}
+
+// llmsHandler serves the output of build, which is built once and cached
+// until the content changes (see Invalidate).
+func (inst *Instance) llmsHandler(opts *LLMsOptions, full bool, build func(context.Context, *LLMsOptions) ([]byte, error)) http.Handler {
+	if opts == nil {
+		opts = &LLMsOptions{}
+	}
+	key := llmsKey{full: full, opts: *opts}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inst.llmsMu.Lock()
+		out, ok := inst.llms[key]
+		inst.llmsMu.Unlock()
+
+		if !ok {
+			var err error
+			if out, err = build(r.Context(), opts); err != nil {
+				fmt.Println("Error building llms.txt: ", err)
+				response.New(w).InternalServerError()
+				return
+			}
+			inst.llmsMu.Lock()
+			inst.llms[key] = out
+			inst.llmsMu.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(out)
+	})
+}
@@ -0,0 +1,80 @@
+package fsmarkdown
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLLMsTxtHeader(t *testing.T) {
+	page := &fstest.MapFile{Data: []byte("---\ntitle: Intro\ndescription: Start here\n---\nHi")}
+	home := &fstest.MapFile{Data: []byte("---\ntitle: Home\ndescription: About the site\n---\n")}
+
+	tests := []struct {
+		name     string
+		home     bool
+		opts     LLMsOptions
+		wantHead string
+	}{
+		{name: "site name", home: true, opts: LLMsOptions{SiteName: "Site", Summary: "Docs"}, wantHead: "# Site\n\n> Docs\n\n"},
+		{name: "home page", home: true, wantHead: "# Home\n\n> About the site\n\n"},
+		{name: "no home page", opts: LLMsOptions{SiteName: "Site"}, wantHead: "# Site\n\n"},
+		{name: "no title", wantHead: "## Pages\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"markdown/intro.md": page}
+			if tt.home {
+				fsys["markdown/_index.md"] = home
+			}
+			out, err := New(fsys).LLMsTxt(context.Background(), &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(out); !strings.HasPrefix(got, tt.wantHead) {
+				t.Errorf("LLMsTxt() = %q, want prefix %q", got, tt.wantHead)
+			}
+			if strings.Contains(string(out), "Error") {
+				t.Errorf("LLMsTxt() = %q, want no error page title", out)
+			}
+		})
+	}
+}
+
+func TestLLMsTxtHandlerCaches(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/_index.md": {Data: []byte("---\ntitle: Home\n---\n")},
+		"markdown/a.md":      {Data: []byte("---\ntitle: A\n---\n")},
+	}
+	inst := New(fsys)
+	txt := inst.LLMsTxtHandler(nil)
+	full := inst.LLMsFullTxtHandler(nil)
+
+	get := func(h http.Handler) string {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Body.String()
+	}
+
+	first, firstFull := get(txt), get(full)
+	if first == firstFull {
+		t.Fatalf("llms.txt and llms-full.txt share a cache entry: %q", first)
+	}
+
+	fsys["markdown/b.md"] = &fstest.MapFile{Data: []byte("---\ntitle: B\n---\n")}
+	if got := get(txt); got != first {
+		t.Errorf("llms.txt rebuilt before Invalidate: %q", got)
+	}
+
+	inst.Invalidate()
+	if got := get(txt); !strings.Contains(got, "[B]") {
+		t.Errorf("llms.txt after Invalidate = %q, want it to list B", got)
+	}
+	if got := get(full); got == firstFull {
+		t.Errorf("llms-full.txt not rebuilt after Invalidate: %q", got)
+	}
+}
@@ -0,0 +1,67 @@
+package fsmarkdown
+
+import (
+	"context"
+	"io/fs"
+	"path"
+)
+
+type NavNode struct {
+	Title    string     `json:"title"`
+	URL      string     `json:"url"`
+	IsFolder bool       `json:"isFolder,omitempty"`
+	Children []*NavNode `json:"children,omitempty"`
+}
+
+// NavTree returns the navigation tree rooted at cleanPath, which must be
+// "/" or a folder (a directory with an _index.md). Children are ordered the
+// same way as in the sitemap.
+func (inst *Instance) NavTree(cleanPath string) (*NavNode, error) {
+	return inst.navTree(context.Background(), cleanPath)
+}
+
+func (inst *Instance) navTree(ctx context.Context, cleanPath string) (*NavNode, error) {
+	cleanPath = path.Clean("/" + cleanPath)
+
+	if inst.bundle != nil && inst.bundle.Nav != nil {
+		if node := inst.bundle.Nav.find(cleanPath); node != nil && node.IsFolder {
+			return node, nil
+		}
+		return nil, &fs.PathError{Op: "readdir", Path: path.Join("markdown", cleanPath), Err: fs.ErrNotExist}
+	}
+
+	root, found, err := inst.getPageBase(ctx, cleanPath)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &NavNode{Title: root.Title, URL: cleanPath, IsFolder: true}
+	if cleanPath == "/" && (!found || node.Title == "") {
+		node.Title = "Home"
+	}
+
+	if err := inst.fillNavChildren(ctx, node); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func (inst *Instance) fillNavChildren(ctx context.Context, node *NavNode) error {
+	pages, _, err := inst.listSection(ctx, node.URL)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pages {
+		child := &NavNode{Title: p.Title, URL: p.URL, IsFolder: p.IsFolder}
+		if p.IsFolder {
+			if err := inst.fillNavChildren(ctx, child); err != nil {
+				return err
+			}
+		}
+		node.Children = append(node.Children, child)
+	}
+
+	return nil
+}
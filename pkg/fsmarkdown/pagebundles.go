@@ -0,0 +1,176 @@
+package fsmarkdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/sjc5/kit/pkg/response"
+)
+
+// With Options.PageBundles set, a page bundle is a directory holding an
+// index.md, e.g.
+// markdown/guide/setup/index.md, which is the page at /guide/setup, and the
+// page's assets (e.g. markdown/guide/setup/diagram.png), which are served
+// under the page's URL by BundleAssetsMiddleware. Relative image and link
+// destinations in a bundle's index.md resolve against the bundle directory,
+// so ![Diagram](diagram.png) points at /guide/setup/diagram.png. Bundle
+// images are not resized (see ImageOptions), which only covers the public
+// static dir.
+//
+// A directory with an _index.md is a folder, not a bundle; an index.md in
+// it is an ordinary page at .../index, as is every index.md when
+// PageBundles is off.
+const bundleIndexName = "index.md"
+
+// isBundleIndex reports whether filePath (within FS) is a page bundle's
+// index.md.
+func (inst *Instance) isBundleIndex(filePath string) bool {
+	if !inst.opts.PageBundles {
+		return false
+	}
+	dir, name := path.Split(filePath)
+	if name != bundleIndexName || path.Clean(dir) == "markdown" {
+		return false
+	}
+	_, err := fs.Stat(inst.FS, path.Join(dir, "_index.md"))
+	return err != nil
+}
+
+// sourcePathForFile returns the source path of the page defined by
+// filePath, a .md file within FS (e.g. "markdown/docs/_index.md" is
+// "/docs").
+func (inst *Instance) sourcePathForFile(filePath string) (sourcePath string, isFolder bool) {
+	rel := strings.TrimPrefix(filePath, "markdown")
+	switch {
+	case path.Base(rel) == "_index.md":
+		return path.Dir(rel), true
+	case inst.isBundleIndex(filePath):
+		return path.Dir(rel), false
+	default:
+		return strings.TrimSuffix(rel, ".md"), false
+	}
+}
+
+// inPageBundle reports whether filePath (within FS) is in a page bundle's
+// directory or one of its subdirectories.
+func (inst *Instance) inPageBundle(filePath string) bool {
+	for dir := path.Dir(filePath); dir != "markdown" && dir != "."; dir = path.Dir(dir) {
+		if inst.isBundleIndex(path.Join(dir, bundleIndexName)) {
+			if _, err := fs.Stat(inst.FS, path.Join(dir, bundleIndexName)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bundleAsset returns the file, within FS, served at urlPath by the page
+// bundle it is in (directly or in a subdirectory), if any. Markdown files
+// are never served as assets.
+func (inst *Instance) bundleAsset(ctx context.Context, urlPath string) (string, bool, error) {
+	urlPath = path.Clean("/" + urlPath)
+	if path.Ext(urlPath) == "" || strings.HasSuffix(urlPath, ".md") {
+		return "", false, nil
+	}
+	if !inst.opts.PageBundles {
+		return "", false, nil
+	}
+	bundleDirs, err := inst.getBundleDirs()
+	if err != nil || len(bundleDirs) == 0 {
+		return "", false, err
+	}
+
+	for dir := path.Dir(urlPath); dir != "/"; dir = path.Dir(dir) {
+		p, found, err := inst.resolvePage(ctx, dir)
+		if err != nil {
+			return "", false, err
+		}
+		if !found {
+			continue
+		}
+		if p.IsFolder || !bundleDirs[path.Dir(p.FilePath)] {
+			return "", false, nil
+		}
+
+		filePath := path.Join(path.Dir(p.FilePath), strings.TrimPrefix(urlPath, dir))
+		info, err := fs.Stat(inst.FS, filePath)
+		if err != nil || !info.Mode().IsRegular() {
+			return "", false, nil
+		}
+		return filePath, true, nil
+	}
+
+	return "", false, nil
+}
+
+// getBundleDirs returns the directories, within FS, of every page bundle.
+// It is built once, on the first asset lookup, so requests on a site
+// without bundles don't look up pages at all. An error is cached too, so
+// it isn't retried on every request until the next Invalidate.
+func (inst *Instance) getBundleDirs() (map[string]bool, error) {
+	inst.bundleDirsMu.Lock()
+	defer inst.bundleDirsMu.Unlock()
+
+	if inst.bundleDirs != nil {
+		return inst.bundleDirs, inst.bundleDirsErr
+	}
+
+	dirs := map[string]bool{}
+	err := fs.WalkDir(inst.FS, "markdown", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && inst.isBundleIndex(p) {
+			dirs[path.Dir(p)] = true
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil // no markdown directory, so no bundles
+	}
+	if err != nil {
+		dirs = map[string]bool{}
+	}
+	inst.bundleDirs, inst.bundleDirsErr = dirs, err
+
+	return dirs, err
+}
+
+// BundleAssetsMiddleware serves the assets of page bundles under their
+// pages' URLs. Other requests pass through to next.
+func (inst *Instance) BundleAssetsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filePath, found, err := inst.bundleAsset(r.Context(), r.URL.Path)
+		if err != nil {
+			fmt.Println("Error looking up bundle asset in BundleAssetsMiddleware: ", err)
+			response.New(w).InternalServerError()
+			return
+		}
+		if !found {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.ServeFileFS(w, r, inst.FS, filePath)
+	})
+}
+
+// resolveBundleURL resolves a relative destination in a bundle's index.md
+// against pageURL, the bundle's URL. Absolute, remote and fragment-only
+// destinations are left alone.
+func resolveBundleURL(pageURL, dest string) (string, bool) {
+	if dest == "" || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "#") || strings.HasPrefix(dest, "?") {
+		return "", false
+	}
+	ref, err := url.Parse(dest)
+	if err != nil || ref.Scheme != "" || ref.Host != "" {
+		return "", false
+	}
+	base := &url.URL{Path: strings.TrimSuffix(pageURL, "/") + "/"}
+	return base.ResolveReference(ref).String(), true
+}
@@ -0,0 +1,110 @@
+package fsmarkdown
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestBundleAssetsMiddleware(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/docs/_index.md":            {Data: []byte("---\ntitle: Docs\n---\n")},
+		"markdown/docs/folder.png":           {Data: []byte("folder")},
+		"markdown/docs/setup/index.md":       {Data: []byte("---\ntitle: Setup\n---\n")},
+		"markdown/docs/setup/diagram.png":    {Data: []byte("diagram")},
+		"markdown/docs/setup/img/nested.png": {Data: []byte("nested")},
+		"markdown/docs/setup/notes.md":       {Data: []byte("---\ntitle: Notes\n---\n")},
+		"markdown/docs/plain.md":             {Data: []byte("---\ntitle: Plain\n---\n")},
+		"markdown/docs/plain/attachment.png": {Data: []byte("attachment")},
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		bundles bool
+		want    string // body served, or "" if passed through
+	}{
+		{name: "asset", path: "/docs/setup/diagram.png", bundles: true, want: "diagram"},
+		{name: "nested asset", path: "/docs/setup/img/nested.png", bundles: true, want: "nested"},
+		{name: "markdown file", path: "/docs/setup/notes.md", bundles: true},
+		{name: "missing asset", path: "/docs/setup/nope.png", bundles: true},
+		{name: "folder", path: "/docs/folder.png", bundles: true},
+		{name: "ordinary page", path: "/docs/plain/attachment.png", bundles: true},
+		{name: "bundles off", path: "/docs/setup/diagram.png"},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := NewWithOptions(&Options{FS: fsys, PageBundles: tt.bundles})
+			rec := httptest.NewRecorder()
+			inst.BundleAssetsMiddleware(next).ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+
+			if tt.want == "" {
+				if rec.Code != http.StatusTeapot {
+					t.Errorf("status = %d, want the request passed through", rec.Code)
+				}
+				return
+			}
+			if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+				t.Errorf("response = %d %q, want 200 %q", rec.Code, rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+// brokenDirFS fails to open one directory, counting the attempts.
+type brokenDirFS struct {
+	fs.FS
+	dir   string
+	opens int
+}
+
+func (b *brokenDirFS) Open(name string) (fs.File, error) {
+	if name == b.dir {
+		b.opens++
+		return nil, errors.New("broken")
+	}
+	return b.FS.Open(name)
+}
+
+func TestBundleDirsErrorIsCached(t *testing.T) {
+	fsys := &brokenDirFS{
+		FS: fstest.MapFS{
+			"markdown/setup/index.md":    {Data: []byte("---\ntitle: Setup\n---\n")},
+			"markdown/setup/diagram.png": {Data: []byte("diagram")},
+			"markdown/broken/page.md":    {Data: []byte("---\ntitle: Page\n---\n")},
+		},
+		dir: "markdown/broken",
+	}
+	inst := NewWithOptions(&Options{FS: fsys, PageBundles: true})
+
+	for range 3 {
+		if _, err := inst.getBundleDirs(); err == nil {
+			t.Fatal("getBundleDirs() = nil error, want one")
+		}
+	}
+	if fsys.opens != 1 {
+		t.Errorf("walked the broken directory %d times, want 1", fsys.opens)
+	}
+
+	inst.Invalidate()
+	if _, err := inst.getBundleDirs(); err == nil {
+		t.Fatal("getBundleDirs() after Invalidate = nil error, want one")
+	}
+	if fsys.opens != 2 {
+		t.Errorf("walked the broken directory %d times after Invalidate, want 2", fsys.opens)
+	}
+}
+
+func TestBundleDirsWithoutMarkdownDir(t *testing.T) {
+	inst := NewWithOptions(&Options{FS: fstest.MapFS{}, PageBundles: true})
+	if dirs, err := inst.getBundleDirs(); err != nil || len(dirs) != 0 {
+		t.Errorf("getBundleDirs() = %v, %v, want none", dirs, err)
+	}
+}
@@ -0,0 +1,95 @@
+package fsmarkdown
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+type Pagination struct {
+	Page       int    `json:"page"`
+	PageSize   int    `json:"pageSize"`
+	TotalItems int    `json:"totalItems"`
+	TotalPages int    `json:"totalPages"`
+	PrevURL    string `json:"prevURL,omitempty"`
+	NextURL    string `json:"nextURL,omitempty"`
+}
+
+var pagePathRe = regexp.MustCompile(`^(.*)/page/([0-9]+)$`)
+
+// splitPagePath splits "/blog/page/2" into "/blog" and 2.
+func splitPagePath(cleanPath string) (string, int, bool) {
+	m := pagePathRe.FindStringSubmatch(cleanPath)
+	if m == nil {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil || n < 1 {
+		return "", 0, false
+	}
+	base := m[1]
+	if base == "" {
+		base = "/"
+	}
+	return base, n, true
+}
+
+func pageURL(base string, n int) string {
+	if n <= 1 {
+		return base
+	}
+	return fmt.Sprintf("%s/page/%d", base, n)
+}
+
+// sectionPageSize returns the listing page size for the folder at cleanPath,
+// or 0 if its listing is not paginated (including when it isn't a folder).
+// The root listing is never paginated.
+func (inst *Instance) sectionPageSize(ctx context.Context, cleanPath string) (int, error) {
+	if cleanPath == "/" {
+		return 0, nil
+	}
+	p, found, err := inst.getPageBase(ctx, cleanPath)
+	if err != nil {
+		return 0, err
+	}
+	if !found || !p.IsFolder {
+		return 0, nil
+	}
+	if p.PageSize > 0 {
+		return p.PageSize, nil
+	}
+	return max(inst.opts.DefaultPageSize, 0), nil
+}
+
+// paginate slices the index sitemap of dp to the requested page. It returns
+// false if the page is out of range.
+func paginate(dp *DetailedPage, base string, pageNum, pageSize int) bool {
+	if pageNum < 1 {
+		pageNum = 1
+	}
+
+	total := len(dp.IndexSitemap)
+	totalPages := max((total+pageSize-1)/pageSize, 1)
+	if pageNum > totalPages {
+		return false
+	}
+
+	start := (pageNum - 1) * pageSize
+	dp.IndexSitemap = dp.IndexSitemap[start:min(start+pageSize, total)]
+
+	dp.Pagination = &Pagination{
+		Page:       pageNum,
+		PageSize:   pageSize,
+		TotalItems: total,
+		TotalPages: totalPages,
+	}
+	if pageNum > 1 {
+		dp.Pagination.PrevURL = pageURL(base, pageNum-1)
+	}
+	if pageNum < totalPages {
+		dp.Pagination.NextURL = pageURL(base, pageNum+1)
+	}
+
+	return true
+}
@@ -0,0 +1,108 @@
+package fsmarkdown
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitPagePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantBase string
+		wantNum  int
+		wantOK   bool
+	}{
+		{"/blog/page/2", "/blog", 2, true},
+		{"/a/b/page/10", "/a/b", 10, true},
+		{"/page/3", "/", 3, true},
+		{"/blog/page/0", "", 0, false},
+		{"/blog/page/x", "", 0, false},
+		{"/blog/page", "", 0, false},
+		{"/blog", "", 0, false},
+	}
+	for _, tt := range tests {
+		base, n, ok := splitPagePath(tt.path)
+		if base != tt.wantBase || n != tt.wantNum || ok != tt.wantOK {
+			t.Errorf("splitPagePath(%q) = %q, %d, %v, want %q, %d, %v", tt.path, base, n, ok, tt.wantBase, tt.wantNum, tt.wantOK)
+		}
+	}
+}
+
+func TestPaginatedListing(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/_index.md":      {Data: []byte("---\ntitle: Home\n---\n")},
+		"markdown/blog/_index.md": {Data: []byte("---\ntitle: Blog\npage_size: 2\n---\n")},
+		"markdown/docs/_index.md": {Data: []byte("---\ntitle: Docs\n---\n")},
+		"markdown/docs/a.md":      {Data: []byte("---\ntitle: A\n---\n")},
+		"markdown/docs/b.md":      {Data: []byte("---\ntitle: B\n---\n")},
+		"markdown/docs/c.md":      {Data: []byte("---\ntitle: C\n---\n")},
+	}
+	for i := 1; i <= 5; i++ {
+		fsys[fmt.Sprintf("markdown/blog/post%d.md", i)] = &fstest.MapFile{Data: []byte(fmt.Sprintf("---\ntitle: Post %d\ndate: 2024-01-0%d\n---\n", i, i))}
+	}
+
+	tests := []struct {
+		name            string
+		defaultPageSize int
+		path            string
+		pageNum         int
+		wantNotFound    bool
+		wantItems       int
+		wantPagination  *Pagination // nil for an unpaginated listing
+	}{
+		{
+			name: "first page", path: "/blog", wantItems: 2,
+			wantPagination: &Pagination{Page: 1, PageSize: 2, TotalItems: 5, TotalPages: 3, NextURL: "/blog/page/2"},
+		},
+		{
+			name: "middle page", path: "/blog/page/2", wantItems: 2,
+			wantPagination: &Pagination{Page: 2, PageSize: 2, TotalItems: 5, TotalPages: 3, PrevURL: "/blog", NextURL: "/blog/page/3"},
+		},
+		{
+			name: "last page", path: "/blog/page/3", wantItems: 1,
+			wantPagination: &Pagination{Page: 3, PageSize: 2, TotalItems: 5, TotalPages: 3, PrevURL: "/blog/page/2"},
+		},
+		{
+			name: "page query", path: "/blog", pageNum: 3, wantItems: 1,
+			wantPagination: &Pagination{Page: 3, PageSize: 2, TotalItems: 5, TotalPages: 3, PrevURL: "/blog/page/2"},
+		},
+		{name: "out of range", path: "/blog/page/4", wantNotFound: true},
+		{name: "unpaginated section", path: "/docs", wantItems: 3},
+		{name: "page path of unpaginated section", path: "/docs/page/2", wantNotFound: true},
+		{
+			name: "default page size", defaultPageSize: 2, path: "/docs/page/2", wantItems: 1,
+			wantPagination: &Pagination{Page: 2, PageSize: 2, TotalItems: 3, TotalPages: 2, PrevURL: "/docs"},
+		},
+		{name: "frontmatter overrides default", defaultPageSize: 1, path: "/blog", wantItems: 2,
+			wantPagination: &Pagination{Page: 1, PageSize: 2, TotalItems: 5, TotalPages: 3, NextURL: "/blog/page/2"},
+		},
+		{name: "root is never paginated", defaultPageSize: 1, path: "/page/2", wantNotFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := NewWithOptions(&Options{FS: fsys, DefaultPageSize: tt.defaultPageSize})
+			dp, err := inst.getPageDetails(context.Background(), tt.path, tt.pageNum)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dp.IsNotFound() != tt.wantNotFound {
+				t.Fatalf("IsNotFound() = %v, want %v", dp.IsNotFound(), tt.wantNotFound)
+			}
+			if tt.wantNotFound {
+				return
+			}
+			if len(dp.IndexSitemap) != tt.wantItems {
+				t.Errorf("got %d listed items, want %d", len(dp.IndexSitemap), tt.wantItems)
+			}
+			switch {
+			case tt.wantPagination == nil && dp.Pagination != nil:
+				t.Errorf("Pagination = %+v, want nil", dp.Pagination)
+			case tt.wantPagination != nil && (dp.Pagination == nil || *dp.Pagination != *tt.wantPagination):
+				t.Errorf("Pagination = %+v, want %+v", dp.Pagination, tt.wantPagination)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/adrg/frontmatter"
+)
+
+// permalinkFor returns the public URL of p: its source path, unless it is a
+// non-folder page with a "slug" frontmatter override or in a section with a
+// permalink pattern (see Options.Permalinks). A slug must be a single path
+// segment; otherwise the file name is used. If a pattern needs a date the
+// page doesn't have, the slug-based URL is used instead.
+func (inst *Instance) permalinkFor(p *Page) string {
+	url, _ := inst.permalink(p)
+	return url
+}
+
+// permalink is permalinkFor, also describing why p's slug or permalink
+// pattern wasn't used, if it wasn't. Problems are reported by Lint and
+// logged once when the permalink index is built, not on every call.
+func (inst *Instance) permalink(p *Page) (url, problem string) {
+	if p.IsFolder || p.SourcePath == "/" {
+		return p.SourcePath, ""
+	}
+
+	fileName := path.Base(p.SourcePath)
+	slug := fileName
+	if p.Slug != "" {
+		if s := strings.Trim(p.Slug, "/"); s == "" || s == "." || s == ".." || strings.ContainsAny(s, "/\\") {
+			problem = fmt.Sprintf("slug %q is not a single path segment, using %s", p.Slug, fileName)
+		} else {
+			slug = s
+		}
+	}
+	defaultURL := path.Join(path.Dir(p.SourcePath), slug)
+
+	pattern, ok := mostSpecificSection(inst.opts.Permalinks, p.SourcePath)
+	if !ok {
+		return defaultURL, problem
+	}
+
+	date, hasDate := parseDate(p.Date)
+	var missingDate bool
+	segments := strings.Split(pattern, "/")
+	for i, seg := range segments {
+		switch seg {
+		case ":year", ":month", ":day":
+			if !hasDate {
+				missingDate = true
+				continue
+			}
+			segments[i] = map[string]string{
+				":year":  fmt.Sprintf("%04d", date.Year()),
+				":month": fmt.Sprintf("%02d", date.Month()),
+				":day":   fmt.Sprintf("%02d", date.Day()),
+			}[seg]
+		case ":slug":
+			segments[i] = slug
+		case ":filename":
+			segments[i] = fileName
+		}
+	}
+	if missingDate {
+		dateProblem := fmt.Sprintf("no valid date for permalink pattern %s, using %s", pattern, defaultURL)
+		if problem != "" {
+			dateProblem = problem + "; " + dateProblem
+		}
+		return defaultURL, dateProblem
+	}
+
+	return path.Clean("/" + strings.Join(segments, "/")), problem
+}
+
+// resolvePage returns the page served at a request path, like getPageBase,
+// but also finding pages by permalink. Paths of existing files resolve to
+// themselves; otherwise the permalink index is consulted.
+func (inst *Instance) resolvePage(ctx context.Context, cleanPath string) (*Page, bool, error) {
+	p, found, err := inst.getPageBase(ctx, cleanPath)
+	if err != nil || found {
+		return p, found, err
+	}
+
+	index, err := inst.getPermalinkIndex(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if sourcePath, ok := index[cleanPath]; ok {
+		return inst.getPageBase(ctx, sourcePath)
+	}
+
+	return p, false, nil
+}
+
+// getPermalinkIndex maps the URL of every page whose URL differs from its
+// source path back to the source path. It is built once, on the first
+// lookup of a path that isn't a file, from frontmatter alone (pages are not
+// rendered), and concurrent first lookups share the work.
+func (inst *Instance) getPermalinkIndex(ctx context.Context) (map[string]string, error) {
+	inst.permalinkIndexMu.Lock()
+	index := inst.permalinkIndex
+	inst.permalinkIndexMu.Unlock()
+	if index != nil {
+		return index, nil
+	}
+
+	return doShared(ctx, &inst.permalinkGroup, "", func(context.Context) (map[string]string, error) {
+		index, err := inst.buildPermalinkIndex()
+		if err != nil {
+			return nil, err
+		}
+		inst.permalinkIndexMu.Lock()
+		inst.permalinkIndex = index
+		inst.permalinkIndexMu.Unlock()
+		return index, nil
+	})
+}
+
+// buildPermalinkIndex builds the index for getPermalinkIndex, logging
+// permalink problems and collisions. A permalink that is another page's
+// source path is left out (the file wins), and of several pages with the
+// same permalink, the first by source path wins.
+func (inst *Instance) buildPermalinkIndex() (map[string]string, error) {
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return nil, err
+	}
+	isSourcePath := make(map[string]bool, len(paths))
+	for _, sourcePath := range paths {
+		isSourcePath[sourcePath] = true
+	}
+
+	index := map[string]string{}
+	for _, sourcePath := range paths {
+		p, found, err := inst.pageFrontmatter(sourcePath)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		url, problem := p.URL, ""
+		if inst.bundle == nil {
+			url, problem = inst.permalink(p)
+		}
+		if problem != "" {
+			log.Printf("fsmarkdown: page %s: %s", sourcePath, problem)
+		}
+
+		switch {
+		case url == sourcePath:
+		case isSourcePath[url]:
+			log.Printf("fsmarkdown: page %s: permalink %s is the path of another page, which takes precedence", sourcePath, url)
+		case index[url] != "":
+			log.Printf("fsmarkdown: page %s: permalink %s is already used by %s, which takes precedence", sourcePath, url, index[url])
+		default:
+			index[url] = sourcePath
+		}
+	}
+
+	return index, nil
+}
+
+// pageFrontmatter returns the page at sourcePath with only its frontmatter,
+// paths and URL filled in, for corpus-wide tables that shouldn't render
+// every page. Pages whose frontmatter doesn't parse are reported as not
+// found; the error surfaces when the page itself is loaded.
+func (inst *Instance) pageFrontmatter(sourcePath string) (*Page, bool, error) {
+	if inst.bundle != nil {
+		p, ok := inst.bundle.Pages[sourcePath]
+		return p, ok, nil
+	}
+
+	filePath, isFolder, fileBytes, err := inst.readPageFile(sourcePath)
+	if err != nil {
+		return nil, false, err
+	}
+	p := &Page{SourcePath: sourcePath, IsFolder: isFolder, FilePath: filePath}
+	if _, err := frontmatter.Parse(bytes.NewReader(fileBytes), p); err != nil {
+		return nil, false, nil
+	}
+	p.URL = inst.permalinkFor(p)
+	return p, true, nil
+}
+
+// mostSpecificSection returns the value for the longest section key (a URL
+// path like "/blog") that contains cleanPath. "/" contains every path.
+func mostSpecificSection[T any](sections map[string]T, cleanPath string) (T, bool) {
+	var best T
+	bestLen := -1
+	for section, v := range sections {
+		section = path.Clean("/" + section)
+		if section != "/" && cleanPath != section && !strings.HasPrefix(cleanPath, section+"/") {
+			continue
+		}
+		if len(section) > bestLen {
+			best, bestLen = v, len(section)
+		}
+	}
+	return best, bestLen >= 0
+}
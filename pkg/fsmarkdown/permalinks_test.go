@@ -0,0 +1,93 @@
+package fsmarkdown
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPermalink(t *testing.T) {
+	inst := NewWithOptions(&Options{Permalinks: map[string]string{"/blog": "/blog/:year/:month/:slug"}})
+
+	tests := []struct {
+		name        string
+		page        Page
+		want        string
+		wantProblem bool
+	}{
+		{name: "plain", page: Page{SourcePath: "/docs/setup"}, want: "/docs/setup"},
+		{name: "slug", page: Page{SourcePath: "/docs/setup", Slug: "install"}, want: "/docs/install"},
+		{name: "slug slashes trimmed", page: Page{SourcePath: "/docs/setup", Slug: "/install/"}, want: "/docs/install"},
+		{name: "folder ignores slug", page: Page{SourcePath: "/docs", IsFolder: true, Slug: "x"}, want: "/docs"},
+		{name: "root", page: Page{SourcePath: "/"}, want: "/"},
+		{name: "pattern", page: Page{SourcePath: "/blog/hello", Date: "2024-03-05"}, want: "/blog/2024/03/hello"},
+		{name: "pattern with slug", page: Page{SourcePath: "/blog/hello", Date: "2024-03-05", Slug: "hi"}, want: "/blog/2024/03/hi"},
+		{name: "pattern without date", page: Page{SourcePath: "/blog/hello"}, want: "/blog/hello", wantProblem: true},
+		{name: "dot dot slug", page: Page{SourcePath: "/docs/setup", Slug: ".."}, want: "/docs/setup", wantProblem: true},
+		{name: "escaping slug", page: Page{SourcePath: "/docs/setup", Slug: "../../admin"}, want: "/docs/setup", wantProblem: true},
+		{name: "nested slug", page: Page{SourcePath: "/docs/setup", Slug: "a/b"}, want: "/docs/setup", wantProblem: true},
+		{name: "backslash slug", page: Page{SourcePath: "/docs/setup", Slug: `a\b`}, want: "/docs/setup", wantProblem: true},
+		{name: "bad slug in pattern", page: Page{SourcePath: "/blog/hello", Date: "2024-03-05", Slug: "../x"}, want: "/blog/2024/03/hello", wantProblem: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, problem := inst.permalink(&tt.page)
+			if got != tt.want {
+				t.Errorf("permalink() = %q, want %q", got, tt.want)
+			}
+			if (problem != "") != tt.wantProblem {
+				t.Errorf("permalink() problem = %q, want problem: %v", problem, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestResolvePage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/_index.md":       {Data: []byte("---\ntitle: Home\n---\n")},
+		"markdown/docs/setup.md":   {Data: []byte("---\ntitle: Setup\nslug: install\n---\n")},
+		"markdown/docs/a.md":       {Data: []byte("---\ntitle: A\nslug: same\n---\n")},
+		"markdown/docs/b.md":       {Data: []byte("---\ntitle: B\nslug: same\n---\n")},
+		"markdown/docs/shadow.md":  {Data: []byte("---\ntitle: Shadow\nslug: real\n---\n")},
+		"markdown/docs/real.md":    {Data: []byte("---\ntitle: Real\n---\n")},
+		"markdown/docs/escape.md":  {Data: []byte("---\ntitle: Escape\nslug: ../../admin\n---\n")},
+		"markdown/blog/_index.md":  {Data: []byte("---\ntitle: Blog\n---\n")},
+		"markdown/blog/hello.md":   {Data: []byte("---\ntitle: Hello\ndate: 2024-03-05\n---\n")},
+		"markdown/blog/undated.md": {Data: []byte("---\ntitle: Undated\n---\n")},
+	}
+	inst := NewWithOptions(&Options{FS: fsys, Permalinks: map[string]string{"/blog": "/blog/:year/:month/:slug"}})
+
+	tests := []struct {
+		path      string
+		wantTitle string // empty for not found
+	}{
+		{"/docs/install", "Setup"},
+		{"/docs/setup", "Setup"}, // the file path still resolves
+		{"/docs/same", "A"},      // first by source path wins
+		{"/docs/real", "Real"},   // a file wins over a permalink
+		{"/docs/escape", "Escape"},
+		{"/admin", ""},
+		{"/blog/2024/03/hello", "Hello"},
+		{"/blog/undated", "Undated"},
+		{"/nope", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, found, err := inst.resolvePage(context.Background(), tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantTitle == "" {
+				if found {
+					t.Errorf("resolvePage(%q) found %s, want not found", tt.path, p.SourcePath)
+				}
+				return
+			}
+			if !found || p.Title != tt.wantTitle {
+				t.Errorf("resolvePage(%q) = %v (found %v), want %q", tt.path, p, found, tt.wantTitle)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package fsmarkdown
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// defaultDescriptionLength matches the lint rule's default limit.
+const defaultDescriptionLength = 160
+
+// PlainText renders markdown to plain text: the text of each block
+// (paragraphs, headings, list items, table cells and code blocks),
+// separated by blank lines, with markup, raw HTML and images (alt text
+// included) dropped. Callouts become their title and body.
+// Frontmatter is not stripped, and shortcodes are not expanded.
+func PlainText(src []byte) string {
+	var inst Instance // default options
+	return inst.renderMarkdown(src, "").text
+}
+
+// plainText returns the document text (see PlainText) and, separately,
+// the text of its paragraphs alone.
+func plainText(ast *blackfriday.Node) (string, string) {
+	var blocks, paragraphs []string
+	var current *strings.Builder
+
+	ast.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		switch n.Type {
+		case blackfriday.Paragraph, blackfriday.Heading, blackfriday.TableCell:
+			if entering {
+				current = &strings.Builder{}
+				return blackfriday.GoToNext
+			}
+			if block := strings.TrimSpace(current.String()); block != "" {
+				blocks = append(blocks, block)
+				if n.Type == blackfriday.Paragraph && !strings.HasPrefix(block, "fsmarkdownadmonition") {
+					paragraphs = append(paragraphs, block)
+				}
+			}
+			current = nil
+		case blackfriday.Image:
+			// Alt text describes the image, it isn't prose
+			return blackfriday.SkipChildren
+		case blackfriday.CodeBlock:
+			if block := strings.TrimSpace(string(n.Literal)); block != "" {
+				blocks = append(blocks, block)
+			}
+		case blackfriday.Text, blackfriday.Code:
+			if entering && current != nil {
+				current.Write(n.Literal)
+			}
+		case blackfriday.Softbreak:
+			if current != nil {
+				current.WriteByte(' ')
+			}
+		case blackfriday.Hardbreak:
+			if current != nil {
+				current.WriteByte('\n')
+			}
+		}
+		return blackfriday.GoToNext
+	})
+
+	return strings.Join(blocks, "\n\n"), strings.Join(paragraphs, " ")
+}
+
+// summarize shortens s to at most n characters, ending at the last
+// sentence boundary that keeps at least a third of the text, or else at a
+// word boundary with an ellipsis.
+func summarize(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	// Byte offset of rune n (and of rune n-1, leaving room for "…")
+	var limit, limitForEllipsis, count int
+	for i := range s {
+		if count == n-1 {
+			limitForEllipsis = i
+		}
+		if count == n {
+			limit = i
+			break
+		}
+		count++
+	}
+
+	cut := s[:limit]
+	for i := len(cut) - 1; i >= len(cut)/3; i-- {
+		if strings.IndexByte(".!?", cut[i]) >= 0 && (i+1 == len(s) || s[i+1] == ' ') {
+			return cut[:i+1]
+		}
+	}
+
+	cut = s[:limitForEllipsis]
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, ",;:-") + "…"
+}
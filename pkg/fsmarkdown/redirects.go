@@ -0,0 +1,129 @@
+package fsmarkdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+)
+
+// Redirects returns the corpus-wide redirect table, mapping each alias
+// declared in frontmatter (aliases: [/old/path]) to its page's URL. Pages
+// whose permalink differs from their file path also get an implicit redirect
+// from the file path. Aliases that collide with a real page, or that are
+// claimed by more than one page, are left out of the table and reported in
+// the returned error. The table is built once, from frontmatter alone
+// (pages are not rendered), and cached along with its error.
+func (inst *Instance) Redirects() (map[string]string, error) {
+	inst.redirectsMu.Lock()
+	table, err := inst.redirects, inst.redirectsErr
+	inst.redirectsMu.Unlock()
+	if table != nil {
+		return table, err
+	}
+
+	type result struct {
+		table map[string]string
+		err   error
+	}
+	res, _ := doShared(context.Background(), &inst.redirectsGroup, "", func(context.Context) (result, error) {
+		table, err := inst.buildRedirects()
+		if table == nil {
+			table = map[string]string{}
+		}
+		inst.redirectsMu.Lock()
+		inst.redirects, inst.redirectsErr = table, err
+		inst.redirectsMu.Unlock()
+		return result{table, err}, nil
+	})
+	return res.table, res.err
+}
+
+func (inst *Instance) buildRedirects() (map[string]string, error) {
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*Page
+	isPage := map[string]bool{}
+	for _, cleanPath := range paths {
+		p, found, err := inst.pageFrontmatter(cleanPath)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			pages = append(pages, p)
+			isPage[p.URL] = true
+		}
+	}
+
+	claims := map[string][]string{}
+	moved := map[string]string{}
+
+	for _, p := range pages {
+		if p.SourcePath != p.URL && !isPage[p.SourcePath] {
+			moved[p.SourcePath] = p.URL
+		}
+		for _, alias := range p.Aliases {
+			alias = path.Clean("/" + alias)
+			claims[alias] = append(claims[alias], p.URL)
+		}
+	}
+
+	aliases := make([]string, 0, len(claims))
+	for alias := range claims {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	table := map[string]string{}
+	var errs []error
+	for _, alias := range aliases {
+		targets := claims[alias]
+		switch {
+		case len(targets) == 1 && alias == targets[0]:
+			// Harmless self-alias
+		case isPage[alias]:
+			errs = append(errs, fmt.Errorf("fsmarkdown: alias %s (on %v) conflicts with an existing page", alias, targets))
+		case len(targets) > 1:
+			errs = append(errs, fmt.Errorf("fsmarkdown: alias %s is claimed by multiple pages: %v", alias, targets))
+		default:
+			table[alias] = targets[0]
+		}
+	}
+
+	for from, to := range moved {
+		if _, ok := claims[from]; !ok {
+			table[from] = to
+		}
+	}
+
+	return table, errors.Join(errs...)
+}
+
+// RedirectMiddleware issues 301s for request paths that are aliases of a
+// page, before the request reaches any page handler. Conflicting aliases are
+// logged and skipped.
+func (inst *Instance) RedirectMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table, err := inst.Redirects()
+		if err != nil {
+			inst.redirectsLogOnce.Do(func() {
+				fmt.Println("Error building redirect table in RedirectMiddleware: ", err)
+			})
+		}
+
+		if target, ok := table[path.Clean(r.URL.Path)]; ok {
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,74 @@
+package fsmarkdown
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRedirectMiddleware(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/_index.md":      {Data: []byte("---\ntitle: Home\n---\n")},
+		"markdown/docs/setup.md":  {Data: []byte("---\ntitle: Setup\naliases: [/install, /old/setup/]\n---\n")},
+		"markdown/docs/moved.md":  {Data: []byte("---\ntitle: Moved\nslug: renamed\n---\n")},
+		"markdown/docs/a.md":      {Data: []byte("---\ntitle: A\naliases: [/shared]\n---\n")},
+		"markdown/docs/b.md":      {Data: []byte("---\ntitle: B\naliases: [/shared, /docs/a]\n---\n")},
+		"markdown/docs/broken.md": {Data: []byte(`{{< nope >}}`)},
+		"markdown/docs/bad.md":    {Data: []byte("---\ntitle: [unclosed\n---\n")},
+	}
+	inst := New(fsys)
+
+	tests := []struct {
+		path string
+		want string // Location, or empty for no redirect
+	}{
+		{"/install", "/docs/setup"},
+		{"/old/setup", "/docs/setup"},
+		{"/old/setup/", "/docs/setup"},
+		{"/install?x=1", "/docs/setup?x=1"},
+		{"/docs/moved", "/docs/renamed"},
+		{"/shared", ""}, // claimed twice
+		{"/docs/a", ""}, // a real page
+		{"/docs/setup", ""},
+	}
+
+	handler := inst.RedirectMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if tt.want == "" {
+				if rec.Code != http.StatusTeapot {
+					t.Errorf("got %d to %q, want no redirect", rec.Code, rec.Header().Get("Location"))
+				}
+				return
+			}
+			if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != tt.want {
+				t.Errorf("got %d to %q, want 301 to %q", rec.Code, rec.Header().Get("Location"), tt.want)
+			}
+		})
+	}
+
+	if _, err := inst.Redirects(); err == nil {
+		t.Error("Redirects() = nil error, want the conflicting aliases")
+	}
+	if got := inst.Stats().BasePage.Size; got != 0 {
+		t.Errorf("%d pages were rendered to build the table, want 0", got)
+	}
+}
+
+func TestRedirectsCachesErrors(t *testing.T) {
+	inst := New(fstest.MapFS{}) // no markdown directory
+	table, err := inst.Redirects()
+	if err == nil || table == nil {
+		t.Fatalf("Redirects() = %v, %v, want an empty table and an error", table, err)
+	}
+
+	inst.FS = fstest.MapFS{"markdown/_index.md": {Data: []byte("hi")}}
+	if _, err2 := inst.Redirects(); err2 != err {
+		t.Errorf("second Redirects() = %v, want the cached %v", err2, err)
+	}
+}
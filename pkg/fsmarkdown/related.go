@@ -0,0 +1,122 @@
+package fsmarkdown
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+)
+
+type relatedCandidate struct {
+	page  *Page
+	score float64
+}
+
+// Related returns up to n pages related to page, best first. Pages are
+// scored by shared tags, being in the same section, and overlap between
+// their content terms. Folders and the page itself are never returned.
+// Scores for a page are computed once and cached until Invalidate.
+func (inst *Instance) Related(page *Page, n int) ([]*Page, error) {
+	inst.relatedMu.Lock()
+	candidates, ok := inst.related[page.URL]
+	inst.relatedMu.Unlock()
+
+	if !ok {
+		var err error
+		candidates, err = inst.scoreRelated(page)
+		if err != nil {
+			return nil, err
+		}
+		inst.relatedMu.Lock()
+		inst.related[page.URL] = candidates
+		inst.relatedMu.Unlock()
+	}
+
+	if n > len(candidates) || n < 0 {
+		n = len(candidates)
+	}
+	related := make([]*Page, 0, n)
+	for _, c := range candidates[:n] {
+		related = append(related, c.page)
+	}
+
+	return related, nil
+}
+
+func (inst *Instance) scoreRelated(page *Page) ([]relatedCandidate, error) {
+	docs, err := inst.getSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	termsByURL := make(map[string]map[string]bool, len(docs))
+	for _, doc := range docs {
+		termsByURL[doc.URL] = toSet(doc.Terms)
+	}
+
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	tags := normalizedTags(page)
+	section := path.Dir(page.SourcePath)
+	terms := termsByURL[page.URL]
+
+	var candidates []relatedCandidate
+	for _, cleanPath := range paths {
+		other, found, err := inst.getListedPage(context.Background(), cleanPath)
+		if err != nil {
+			return nil, err
+		}
+		if !found || other.IsFolder || other.URL == page.URL {
+			continue
+		}
+
+		var score float64
+		for tag := range normalizedTags(other) {
+			if tags[tag] {
+				score += 3
+			}
+		}
+		if path.Dir(other.SourcePath) == section {
+			score += 1
+		}
+		score += 5 * jaccard(terms, termsByURL[other.URL])
+
+		if score > 0 {
+			candidates = append(candidates, relatedCandidate{page: other, score: score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.page.effectiveDate().After(b.page.effectiveDate())
+	})
+
+	return candidates, nil
+}
+
+func normalizedTags(p *Page) map[string]bool {
+	tags := make(map[string]bool, len(p.Tags))
+	for _, tag := range p.Tags {
+		tags[strings.ToLower(strings.TrimSpace(tag))] = true
+	}
+	return tags
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for term := range a {
+		if b[term] {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
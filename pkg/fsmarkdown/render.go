@@ -0,0 +1,124 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+const markdownExtensions = blackfriday.CommonExtensions
+
+type TOCItem struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	ID    string `json:"id,omitempty"` // see Options.HeadingIDs
+}
+
+type renderOutput struct {
+	html   []byte
+	toc    []TOCItem
+	text   string   // plain text, see PlainText
+	prose  string   // text of paragraphs only, for descriptions
+	images []string // local image paths, see localImagePath
+}
+
+// renderMarkdown renders src to HTML (equivalent to blackfriday.Run, plus
+// callouts and, with Options.HeadingIDs, heading IDs) and collects the
+// table of contents and the document text from the same AST. Headings
+// inside callouts are not part of the table of contents. If bundleURL is
+// set, src is a page bundle's index.md (see bundleIndexName) and its
+// relative links and images are resolved against bundleURL.
+func (inst *Instance) renderMarkdown(src []byte, bundleURL string) *renderOutput {
+	src, admonitions := extractAdmonitions(src)
+
+	extensions := markdownExtensions
+	if inst.opts.HeadingIDs {
+		extensions |= blackfriday.AutoHeadingIDs
+	}
+	parser := blackfriday.New(blackfriday.WithExtensions(extensions))
+	ast := parser.Parse(src)
+
+	out := &renderOutput{toc: collectTOC(ast)}
+	out.text, out.prose = plainText(ast)
+
+	r := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+		Flags: blackfriday.CommonHTMLFlags,
+	})
+
+	var buf bytes.Buffer
+	r.RenderHeader(&buf, ast)
+	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if bundleURL != "" && entering && (node.Type == blackfriday.Image || node.Type == blackfriday.Link) {
+			if dest, ok := resolveBundleURL(bundleURL, string(node.LinkData.Destination)); ok {
+				node.LinkData.Destination = []byte(dest)
+				return r.RenderNode(&buf, node, entering)
+			}
+		}
+		if node.Type == blackfriday.Image && entering {
+			if publicPath, ok := localImagePath(string(node.LinkData.Destination)); ok {
+				out.images = append(out.images, publicPath)
+			}
+			if inst.renderImage(&buf, node) {
+				return blackfriday.SkipChildren
+			}
+		}
+		return r.RenderNode(&buf, node, entering)
+	})
+	r.RenderFooter(&buf, ast)
+	out.html = buf.Bytes()
+
+	inst.insertAdmonitions(out, admonitions, bundleURL)
+
+	return out
+}
+
+func collectTOC(ast *blackfriday.Node) []TOCItem {
+	var toc []TOCItem
+	seenIDs := map[string]int{}
+
+	ast.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.Heading || node.IsTitleblock {
+			return blackfriday.GoToNext
+		}
+		item := TOCItem{Level: node.Level, Text: nodeText(node)}
+		if node.HeadingID != "" {
+			item.ID = uniqueHeadingID(seenIDs, node.HeadingID)
+		}
+		toc = append(toc, item)
+		return blackfriday.SkipChildren
+	})
+
+	return toc
+}
+
+// uniqueHeadingID mirrors blackfriday's de-duplication of auto heading IDs,
+// so that TOC anchors match the rendered ids.
+func uniqueHeadingID(seen map[string]int, id string) string {
+	for count, found := seen[id]; found; count, found = seen[id] {
+		tmp := fmt.Sprintf("%s-%d", id, count+1)
+		if _, tmpFound := seen[tmp]; !tmpFound {
+			seen[id] = count + 1
+			id = tmp
+		} else {
+			id = id + "-1"
+		}
+	}
+	if _, found := seen[id]; !found {
+		seen[id] = 0
+	}
+	return id
+}
+
+// nodeText concatenates the literal text of node's descendants.
+func nodeText(node *blackfriday.Node) string {
+	var sb strings.Builder
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+			sb.Write(n.Literal)
+		}
+		return blackfriday.GoToNext
+	})
+	return sb.String()
+}
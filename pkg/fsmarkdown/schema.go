@@ -0,0 +1,307 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adrg/frontmatter"
+)
+
+type FieldType string
+
+const (
+	FieldTypeString     FieldType = "string"
+	FieldTypeInt        FieldType = "int"
+	FieldTypeFloat      FieldType = "float"
+	FieldTypeBool       FieldType = "bool"
+	FieldTypeDate       FieldType = "date"
+	FieldTypeStringList FieldType = "[]string"
+)
+
+type Field struct {
+	// If empty, any type is accepted.
+	Type     FieldType
+	Required bool
+	// If set, the value (or each list item) must be one of these.
+	Enum []string
+	// Go time layout for FieldTypeDate. If empty, any of the date formats
+	// accepted elsewhere in this package is allowed.
+	DateFormat string
+}
+
+type Schema struct {
+	Fields map[string]Field
+	// If true, keys not declared in Fields are reported.
+	DisallowUnknown bool
+}
+
+// FrontmatterError describes a single frontmatter problem. Line is 1-based
+// and refers to the whole file, not just the frontmatter block.
+type FrontmatterError struct {
+	File  string
+	Line  int
+	Field string
+	Msg   string
+}
+
+func (e *FrontmatterError) Error() string {
+	loc := e.File
+	if e.Line > 0 {
+		loc += ":" + strconv.Itoa(e.Line)
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("%s: %s: %s", loc, e.Field, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", loc, e.Msg)
+}
+
+// Validate checks every page's frontmatter against the configured schemas,
+// and that pages with valid frontmatter render (see getListedPage), and
+// returns all problems found, joined. Caches are bypassed.
+func (inst *Instance) Validate() error {
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, cleanPath := range paths {
+		filePath, isFolder, fileBytes, err := inst.readPageFile(cleanPath)
+		if err != nil {
+			return err
+		}
+		if _, err := frontmatter.Parse(bytes.NewReader(fileBytes), &Page{}); err != nil {
+			errs = append(errs, toFrontmatterParseError(filePath, err))
+			continue
+		}
+		if fmErrs := inst.validateFrontmatter(fileBytes, cleanPath, filePath); len(fmErrs) > 0 {
+			errs = append(errs, fmErrs...)
+			continue
+		}
+		if err := inst.checkRender(fileBytes, cleanPath, filePath, isFolder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MustValidate panics if StrictFrontmatter is set and Validate returns an
+// error. It is a no-op otherwise.
+func (inst *Instance) MustValidate() {
+	if !inst.opts.StrictFrontmatter {
+		return
+	}
+	if err := inst.Validate(); err != nil {
+		panic(fmt.Sprintf("fsmarkdown: invalid pages:\n%v", err))
+	}
+}
+
+// schemaFor returns the schema of the most specific section containing
+// cleanPath, or nil.
+func (inst *Instance) schemaFor(cleanPath string) *Schema {
+	schema, _ := mostSpecificSection(inst.opts.Schemas, cleanPath)
+	return schema
+}
+
+func (inst *Instance) validateFrontmatter(fileBytes []byte, cleanPath, filePath string) []error {
+	schema := inst.schemaFor(cleanPath)
+	if schema == nil {
+		return nil
+	}
+
+	var fm map[string]any
+	if _, err := frontmatter.Parse(bytes.NewReader(fileBytes), &fm); err != nil {
+		return []error{toFrontmatterParseError(filePath, err)}
+	}
+
+	lines := frontmatterKeyLines(fileBytes)
+	var errs []error
+	addErr := func(field, msg string) {
+		line, ok := lines[field]
+		if !ok {
+			line = 1
+		}
+		errs = append(errs, &FrontmatterError{File: filePath, Line: line, Field: field, Msg: msg})
+	}
+
+	names := make([]string, 0, len(schema.Fields))
+	for name := range schema.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		field := schema.Fields[name]
+		val, ok := fm[name]
+		if !ok || val == nil {
+			if field.Required {
+				addErr(name, "required field is missing")
+			}
+			continue
+		}
+		if msg := checkField(field, val); msg != "" {
+			addErr(name, msg)
+		}
+	}
+
+	if schema.DisallowUnknown {
+		var unknown []string
+		for name := range fm {
+			if _, ok := schema.Fields[name]; !ok {
+				unknown = append(unknown, name)
+			}
+		}
+		sort.Strings(unknown)
+		for _, name := range unknown {
+			addErr(name, "unknown field")
+		}
+	}
+
+	return errs
+}
+
+func checkField(field Field, val any) string {
+	var strs []string
+
+	switch field.Type {
+	case "":
+		if s, ok := val.(string); ok {
+			strs = []string{s}
+		}
+	case FieldTypeString:
+		s, ok := val.(string)
+		if !ok {
+			return fmt.Sprintf("expected string, got %T", val)
+		}
+		strs = []string{s}
+	case FieldTypeInt:
+		switch v := val.(type) {
+		case int, int64, uint64:
+		case float64:
+			if v != float64(int64(v)) {
+				return fmt.Sprintf("expected int, got %v", v)
+			}
+		default:
+			return fmt.Sprintf("expected int, got %T", val)
+		}
+	case FieldTypeFloat:
+		switch val.(type) {
+		case int, int64, uint64, float64:
+		default:
+			return fmt.Sprintf("expected float, got %T", val)
+		}
+	case FieldTypeBool:
+		if _, ok := val.(bool); !ok {
+			return fmt.Sprintf("expected bool, got %T", val)
+		}
+	case FieldTypeDate:
+		switch v := val.(type) {
+		case time.Time:
+		case string:
+			if field.DateFormat != "" {
+				if _, err := time.Parse(field.DateFormat, v); err != nil {
+					return fmt.Sprintf("expected date in format %q, got %q", field.DateFormat, v)
+				}
+			} else if _, ok := parseDate(v); !ok {
+				return fmt.Sprintf("unrecognized date %q", v)
+			}
+		default:
+			return fmt.Sprintf("expected date, got %T", val)
+		}
+	case FieldTypeStringList:
+		list, ok := val.([]any)
+		if !ok {
+			return fmt.Sprintf("expected list of strings, got %T", val)
+		}
+		for i, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Sprintf("expected list of strings, item %d is %T", i, item)
+			}
+			strs = append(strs, s)
+		}
+	default:
+		return fmt.Sprintf("schema has unknown field type %q", field.Type)
+	}
+
+	if len(field.Enum) > 0 {
+		for _, s := range strs {
+			if !slices.Contains(field.Enum, s) {
+				return fmt.Sprintf("%q is not one of %s", s, strings.Join(field.Enum, ", "))
+			}
+		}
+	}
+
+	return ""
+}
+
+var frontmatterKeyRe = regexp.MustCompile(`^"?([A-Za-z0-9_-]+)"?\s*[:=]`)
+
+// frontmatterKeyLines maps each top-level frontmatter key to its 1-based
+// line number in the file.
+func frontmatterKeyLines(fileBytes []byte) map[string]int {
+	lines := map[string]int{}
+
+	var delim string
+	var isJSON bool
+	for i, line := range strings.Split(string(fileBytes), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if i == 0 {
+			switch start := strings.TrimSpace(line); start {
+			case "---", "---yaml", "---toml":
+				delim = "---"
+			case "---json":
+				delim, isJSON = "---", true
+			case "+++":
+				delim = "+++"
+			case ";;;":
+				delim, isJSON = ";;;", true
+			case "{":
+				delim, isJSON = "}", true
+			default:
+				return lines
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == delim {
+			break
+		}
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			// Indented lines are nested values, except in JSON
+			if !isJSON {
+				continue
+			}
+			line = strings.TrimSpace(line)
+		}
+		if m := frontmatterKeyRe.FindStringSubmatch(line); m != nil {
+			if _, ok := lines[m[1]]; !ok {
+				lines[m[1]] = i + 1
+			}
+		}
+	}
+
+	return lines
+}
+
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// toFrontmatterParseError wraps a frontmatter parse error with the file
+// path and, when the decoder reports one, the line in the file.
+func toFrontmatterParseError(filePath string, err error) error {
+	fmErr := &FrontmatterError{File: filePath, Msg: err.Error()}
+	if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+		if n, convErr := strconv.Atoi(m[1]); convErr == nil {
+			// Decoder lines are relative to the block after the opening delimiter
+			fmErr.Line = n + 1
+		}
+	}
+	return fmErr
+}
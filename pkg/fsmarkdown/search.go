@@ -0,0 +1,147 @@
+package fsmarkdown
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type searchDoc struct {
+	URL      string
+	Title    string
+	Headings []string
+	Terms    []string
+}
+
+type SearchResult struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	Score int    `json:"score"`
+}
+
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "this": true, "to": true, "was": true,
+	"with": true,
+}
+
+// tokenize splits s into unique, lowercased terms, dropping stop words and
+// single characters.
+func tokenize(s string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, f := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(f)) < 2 || stopWords[f] || seen[f] {
+			continue
+		}
+		seen[f] = true
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+func newSearchDoc(p *Page) *searchDoc {
+	doc := &searchDoc{URL: p.URL, Title: p.Title, Terms: p.searchTerms}
+	for _, item := range p.TOC {
+		doc.Headings = append(doc.Headings, item.Text)
+	}
+	return doc
+}
+
+// Search returns up to limit pages matching query, best first. Title matches
+// outweigh heading matches, which outweigh body matches.
+func (inst *Instance) Search(query string, limit int) ([]SearchResult, error) {
+	docs, err := inst.getSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return []SearchResult{}, nil
+	}
+
+	results := []SearchResult{}
+	for _, doc := range docs {
+		titleTerms := toSet(tokenize(doc.Title))
+		headingTerms := toSet(tokenize(strings.Join(doc.Headings, " ")))
+		bodyTerms := toSet(doc.Terms)
+
+		score := 0
+		for _, t := range queryTerms {
+			switch {
+			case titleTerms[t]:
+				score += 5
+			case headingTerms[t]:
+				score += 3
+			case bodyTerms[t]:
+				score += 1
+			}
+		}
+		if score > 0 {
+			results = append(results, SearchResult{Title: doc.Title, URL: doc.URL, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+func (inst *Instance) getSearchIndex() ([]*searchDoc, error) {
+	if inst.bundle != nil {
+		return inst.bundle.Search, nil
+	}
+
+	inst.searchIndexMu.Lock()
+	defer inst.searchIndexMu.Unlock()
+
+	if inst.searchIndex != nil {
+		return inst.searchIndex, nil
+	}
+
+	docs, err := inst.buildSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+	inst.searchIndex = docs
+
+	return docs, nil
+}
+
+func (inst *Instance) buildSearchIndex() ([]*searchDoc, error) {
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]*searchDoc, 0, len(paths))
+	for _, cleanPath := range paths {
+		p, found, err := inst.getListedPage(context.Background(), cleanPath)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			docs = append(docs, newSearchDoc(p))
+		}
+	}
+
+	return docs, nil
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
@@ -0,0 +1,173 @@
+package fsmarkdown
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sjc5/kit/pkg/tsgen"
+)
+
+// SearchIndexFileName is the name WriteSearchIndex's output is meant to
+// be published under, in the public static dir.
+const SearchIndexFileName = "fsmarkdown_search.json"
+
+const searchIndexVersion = 1
+
+// clientSearchIndex is the compact index read by the generated TS
+// fsmarkdownSearch function. Terms maps each stemmed term to a flat list
+// of (doc index, weight) pairs, where the weight is 5 for a title match,
+// 3 for a heading match and 1 for a body match.
+type clientSearchIndex struct {
+	Version int               `json:"v"`
+	Docs    []clientSearchDoc `json:"docs"`
+	Terms   map[string][]int  `json:"terms"`
+}
+
+type clientSearchDoc struct {
+	URL      string   `json:"u"`
+	Title    string   `json:"t"`
+	Headings []string `json:"h,omitempty"`
+}
+
+// WriteSearchIndex writes a compact JSON search index of every page to w,
+// for client-side search on static deploys. Query it with the TS code from
+// AddSearchIndexTS, which tokenizes and stems queries the same way.
+func (inst *Instance) WriteSearchIndex(w io.Writer) error {
+	docs, err := inst.getSearchIndex()
+	if err != nil {
+		return err
+	}
+
+	index := clientSearchIndex{
+		Version: searchIndexVersion,
+		Docs:    make([]clientSearchDoc, 0, len(docs)),
+		Terms:   map[string][]int{},
+	}
+
+	for i, doc := range docs {
+		index.Docs = append(index.Docs, clientSearchDoc{URL: doc.URL, Title: doc.Title, Headings: doc.Headings})
+
+		weights := map[string]int{}
+		addTerms := func(terms []string, weight int) {
+			for _, t := range terms {
+				t = stem(t)
+				weights[t] = max(weights[t], weight)
+			}
+		}
+		addTerms(doc.Terms, 1)
+		addTerms(tokenize(strings.Join(doc.Headings, " ")), 3)
+		addTerms(tokenize(doc.Title), 5)
+
+		for t, weight := range weights {
+			index.Terms[t] = append(index.Terms[t], i, weight)
+		}
+	}
+
+	return json.NewEncoder(w).Encode(index)
+}
+
+// stemRules are tried in order; the first whose suffix matches, leaving at
+// least 3 characters, applies. Mirrored by fsmarkdownStem in searchIndexTS.
+var stemRules = [][2]string{
+	{"sses", "ss"}, {"ies", "y"}, {"ss", "ss"}, {"us", "us"}, {"is", "is"}, {"s", ""},
+}
+
+var stemRules2 = [][2]string{
+	{"ingly", ""}, {"edly", ""}, {"ing", ""}, {"ed", ""}, {"ly", ""},
+}
+
+// stem is a light English suffix stripper, good enough to match "pages"
+// with "page" and "running" with "run".
+func stem(term string) string {
+	term = applyStemRules(term, stemRules)
+	if stemmed := applyStemRules(term, stemRules2); stemmed != term {
+		term = stemmed
+		// hopped -> hopp -> hop, but not fall -> fal
+		if n := len(term); n >= 2 && term[n-1] == term[n-2] && strings.IndexByte("bcdfghjkmnpqrtvwx", term[n-1]) >= 0 {
+			term = term[:n-1]
+		}
+	}
+	return term
+}
+
+func applyStemRules(term string, rules [][2]string) string {
+	for _, rule := range rules {
+		if strings.HasSuffix(term, rule[0]) && utf8.RuneCountInString(term)-len(rule[0]) >= 3 {
+			return strings.TrimSuffix(term, rule[0]) + rule[1]
+		}
+	}
+	return term
+}
+
+// AddSearchIndexTS adds the TS for WriteSearchIndex's output to statements:
+// the FsmarkdownSearchIndex type, the FSMARKDOWN_SEARCH_INDEX_FILE name
+// (a public file map key) and fsmarkdownSearch(index, query, limit).
+func AddSearchIndexTS(statements *tsgen.Statements) {
+	stopList := make([]string, 0, len(stopWords))
+	for w := range stopWords {
+		stopList = append(stopList, w)
+	}
+	sort.Strings(stopList)
+
+	statements.Serialize("export const FSMARKDOWN_SEARCH_INDEX_FILE", SearchIndexFileName)
+	statements.Raw("export type FsmarkdownSearchIndex", searchIndexTSType)
+	statements.Raw("export type FsmarkdownSearchResult", "{ title: string; url: string; score: number }")
+	statements.Serialize("const FSMARKDOWN_STOP_WORDS", stopList)
+	statements.Serialize("const FSMARKDOWN_STEM_RULES", stemRules)
+	statements.Serialize("const FSMARKDOWN_STEM_RULES_2", stemRules2)
+	statements.Raw("const fsmarkdownApplyStemRules", searchIndexTSApplyStemRules)
+	statements.Raw("export const fsmarkdownStem", searchIndexTSStem)
+	statements.Raw("export const fsmarkdownSearch", searchIndexTSSearch)
+}
+
+const searchIndexTSType = `{
+	v: number;
+	docs: Array<{ u: string; t: string; h?: Array<string> }>;
+	terms: Record<string, Array<number>>;
+}`
+
+const searchIndexTSApplyStemRules = `(term: string, rules: ReadonlyArray<readonly [string, string]>): string => {
+	for (const [suffix, replacement] of rules) {
+		if (term.endsWith(suffix) && [...term].length - suffix.length >= 3) {
+			return term.slice(0, term.length - suffix.length) + replacement;
+		}
+	}
+	return term;
+}`
+
+const searchIndexTSStem = `(term: string): string => {
+	term = fsmarkdownApplyStemRules(term, FSMARKDOWN_STEM_RULES);
+	const stemmed = fsmarkdownApplyStemRules(term, FSMARKDOWN_STEM_RULES_2);
+	if (stemmed !== term) {
+		term = stemmed;
+		const last = term[term.length - 1];
+		if (term.length >= 2 && last === term[term.length - 2] && "bcdfghjkmnpqrtvwx".includes(last)) {
+			term = term.slice(0, -1);
+		}
+	}
+	return term;
+}`
+
+const searchIndexTSSearch = `(index: FsmarkdownSearchIndex, query: string, limit: number = 10): Array<FsmarkdownSearchResult> => {
+	const stopWords: ReadonlyArray<string> = FSMARKDOWN_STOP_WORDS;
+	const terms = new Set<string>();
+	for (const t of query.toLowerCase().split(/[^\p{L}\p{Nd}]+/u)) {
+		if ([...t].length >= 2 && !stopWords.includes(t)) {
+			terms.add(fsmarkdownStem(t));
+		}
+	}
+	const scores = new Map<number, number>();
+	for (const term of terms) {
+		const postings = index.terms[term] ?? [];
+		for (let i = 0; i < postings.length; i += 2) {
+			scores.set(postings[i], (scores.get(postings[i]) ?? 0) + postings[i + 1]);
+		}
+	}
+	return [...scores]
+		.sort((a, b) => b[1] - a[1])
+		.slice(0, limit)
+		.map(([i, score]) => ({ title: index.docs[i].t, url: index.docs[i].u, score }));
+}`
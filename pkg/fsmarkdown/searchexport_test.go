@@ -0,0 +1,89 @@
+package fsmarkdown
+
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+var stemCases = map[string]string{
+	"pages":        "page",
+	"running":      "run",
+	"hopped":       "hop",
+	"falling":      "fall",
+	"classes":      "class",
+	"glass":        "glass",
+	"parties":      "party",
+	"status":       "status",
+	"analysis":     "analysis",
+	"gas":          "gas",
+	"quickly":      "quick",
+	"repeatedly":   "repeat",
+	"surprisingly": "surpris",
+	"buzzed":       "buzz",
+	"cafés":        "café",
+	"ies":          "ies",
+	"sing":         "sing",
+}
+
+func TestStem(t *testing.T) {
+	for term, want := range stemCases {
+		if got := stem(term); got != want {
+			t.Errorf("stem(%q) = %q, want %q", term, got, want)
+		}
+	}
+}
+
+// TestStemMatchesTS runs the generated fsmarkdownStem under node and checks
+// it agrees with stem, since WriteSearchIndex stems terms in Go and
+// fsmarkdownSearch stems queries in the browser.
+func TestStemMatchesTS(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+
+	terms := make([]string, 0, len(stemCases))
+	for term := range stemCases {
+		terms = append(terms, term)
+	}
+
+	// The TS is plain JS once its few type annotations are stripped
+	stripTypes := strings.NewReplacer(
+		"(term: string, rules: ReadonlyArray<readonly [string, string]>): string =>", "(term, rules) =>",
+		"(term: string): string =>", "(term) =>",
+	)
+	script := "const FSMARKDOWN_STEM_RULES = " + mustJSON(t, stemRules) + ";\n" +
+		"const FSMARKDOWN_STEM_RULES_2 = " + mustJSON(t, stemRules2) + ";\n" +
+		"const fsmarkdownApplyStemRules = " + stripTypes.Replace(searchIndexTSApplyStemRules) + ";\n" +
+		"const fsmarkdownStem = " + stripTypes.Replace(searchIndexTSStem) + ";\n" +
+		"console.log(JSON.stringify(" + mustJSON(t, terms) + ".map(fsmarkdownStem)));\n"
+
+	out, err := exec.Command(node, "-e", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("node: %v\n%s", err, out)
+	}
+	var got []string
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("decoding node output %q: %v", out, err)
+	}
+	if len(got) != len(terms) {
+		t.Fatalf("node stemmed %d terms, want %d", len(got), len(terms))
+	}
+
+	for i, term := range terms {
+		if want := stem(term); got[i] != want {
+			t.Errorf("fsmarkdownStem(%q) = %q, stem = %q", term, got[i], want)
+		}
+	}
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,148 @@
+package fsmarkdown
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/sjc5/kit/pkg/htmlutil"
+)
+
+type SEOOptions struct {
+	SiteName string
+	// Origin used for canonical and og:url links, e.g. "https://example.com".
+	// If empty, those are omitted.
+	BaseURL string
+	// Used when a page has no "image" frontmatter. Root-relative URLs are
+	// made absolute with BaseURL.
+	DefaultImage string
+	// Twitter handle of the site, e.g. "@example".
+	TwitterSite string
+	// fmt format for the <title>, e.g. "%s | Example". Defaults to "%s".
+	TitleFormat string
+}
+
+// HeadBlocks returns the head elements for a markdown page: title, meta
+// description, canonical link, OpenGraph and Twitter tags, and, for dated
+// non-folder pages, article:published_time and a JSON-LD Article.
+func HeadBlocks(dp *DetailedPage, opts *SEOOptions) []htmlutil.Element {
+	if opts == nil {
+		opts = &SEOOptions{}
+	}
+
+	p := dp.Page
+	titleFormat := opts.TitleFormat
+	if titleFormat == "" {
+		titleFormat = "%s"
+	}
+
+	baseURL := strings.TrimSuffix(opts.BaseURL, "/")
+	var canonicalURL string
+	if baseURL != "" {
+		canonicalURL = baseURL + p.URL
+	}
+
+	image := p.Image
+	if image == "" {
+		image = opts.DefaultImage
+	}
+	if strings.HasPrefix(image, "/") && !strings.HasPrefix(image, "//") {
+		image = baseURL + image
+	}
+
+	publishedAt, hasDate := parseDate(p.Date)
+	isArticle := hasDate && !p.IsFolder
+
+	var blocks []htmlutil.Element
+	meta := func(attr, key, content string) {
+		if content != "" {
+			blocks = append(blocks, htmlutil.Element{
+				Tag:        "meta",
+				Attributes: map[string]string{attr: key, "content": content},
+			})
+		}
+	}
+
+	blocks = append(blocks, htmlutil.Element{Tag: "title", InnerHTML: template.HTML(fmt.Sprintf(titleFormat, p.Title))})
+	meta("name", "description", p.Description)
+
+	if canonicalURL != "" {
+		blocks = append(blocks, htmlutil.Element{
+			Tag:        "link",
+			Attributes: map[string]string{"rel": "canonical", "href": canonicalURL},
+		})
+	}
+
+	ogType := "website"
+	if isArticle {
+		ogType = "article"
+	}
+	meta("property", "og:type", ogType)
+	meta("property", "og:title", p.Title)
+	meta("property", "og:description", p.Description)
+	meta("property", "og:url", canonicalURL)
+	meta("property", "og:site_name", opts.SiteName)
+	meta("property", "og:image", image)
+
+	twitterCard := "summary"
+	if image != "" {
+		twitterCard = "summary_large_image"
+	}
+	meta("name", "twitter:card", twitterCard)
+	meta("name", "twitter:site", opts.TwitterSite)
+	meta("name", "twitter:title", p.Title)
+	meta("name", "twitter:description", p.Description)
+	meta("name", "twitter:image", image)
+
+	if isArticle {
+		meta("property", "article:published_time", publishedAt.Format(time.RFC3339))
+		meta("property", "article:modified_time", p.LastModified)
+
+		if el, err := articleJSONLD(p, canonicalURL, image, publishedAt, opts); err == nil {
+			blocks = append(blocks, el)
+		} else {
+			fmt.Println("Error building JSON-LD in HeadBlocks: ", err)
+		}
+	}
+
+	return blocks
+}
+
+func articleJSONLD(p *Page, canonicalURL, image string, publishedAt time.Time, opts *SEOOptions) (htmlutil.Element, error) {
+	ld := map[string]any{
+		"@context":      "https://schema.org",
+		"@type":         "Article",
+		"headline":      p.Title,
+		"datePublished": publishedAt.Format(time.RFC3339),
+	}
+	if p.LastModified != "" {
+		ld["dateModified"] = p.LastModified
+	}
+	if p.Description != "" {
+		ld["description"] = p.Description
+	}
+	if canonicalURL != "" {
+		ld["url"] = canonicalURL
+		ld["mainEntityOfPage"] = canonicalURL
+	}
+	if image != "" {
+		ld["image"] = image
+	}
+	if opts.SiteName != "" {
+		ld["publisher"] = map[string]any{"@type": "Organization", "name": opts.SiteName}
+	}
+
+	// json.Marshal escapes <, > and &, so the output is safe inside <script>
+	b, err := json.Marshal(ld)
+	if err != nil {
+		return htmlutil.Element{}, err
+	}
+
+	return htmlutil.Element{
+		Tag:        "script",
+		Attributes: map[string]string{"type": "application/ld+json"},
+		InnerHTML:  template.HTML(b),
+	}, nil
+}
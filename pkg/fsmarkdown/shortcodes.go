@@ -0,0 +1,150 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Shortcodes are expanded in the markdown body before it is rendered. They
+// look like {{< name "arg" "arg" >}}, and are left alone inside fenced code
+// blocks. Supported shortcodes:
+//
+//	{{< data "file.key.path" "column" ... >}}
+//	    Inserts a value from a data file (see Instance.Data). Maps and lists
+//	    of maps become tables, optionally limited to the given columns.
+//
+//	{{< include "path/to/fragment.md" >}}
+//	    Inserts another markdown file (see includeShortcode).
+
+var (
+	shortcodeRe    = regexp.MustCompile(`\{\{<\s*([A-Za-z][\w-]*)((?:\s+"[^"]*")*)\s*>\}\}`)
+	shortcodeArgRe = regexp.MustCompile(`"([^"]*)"`)
+)
+
+type shortcodeFunc func(inst *Instance, sc *shortcodeContext, args []string) (string, error)
+
+// shortcodeContext describes the file whose shortcodes are being expanded.
+type shortcodeContext struct {
+	filePath string
+	stack    []string  // files being included, outermost first
+	includes *[]string // every file included so far, for dependency tracking
+	data     *[]string // every data file read so far, for Last-Modified
+}
+
+var shortcodes map[string]shortcodeFunc
+
+func init() {
+	shortcodes = map[string]shortcodeFunc{
+		"data":    dataShortcode,
+		"include": includeShortcode,
+	}
+}
+
+func dataShortcode(inst *Instance, sc *shortcodeContext, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("data shortcode needs a data reference")
+	}
+	v, err := inst.lookupData(args[0])
+	if err != nil {
+		return "", err
+	}
+	if filePath, ok := inst.dataRefFile(args[0]); ok {
+		*sc.data = append(*sc.data, filePath)
+	}
+	return dataToMarkdown(v, args[1:]), nil
+}
+
+// codeChunk is a run of lines from a markdown body, either all inside a
+// fenced code block (including its fences) or all outside one.
+type codeChunk struct {
+	text   []byte
+	fenced bool
+}
+
+// splitFencedCode splits src into runs of lines inside and outside fenced
+// code blocks, so that shortcodes and templates can leave code alone.
+func splitFencedCode(src []byte) []codeChunk {
+	var chunks []codeChunk
+	add := func(line []byte, fenced bool) {
+		if n := len(chunks); n > 0 && chunks[n-1].fenced == fenced {
+			chunks[n-1].text = append(chunks[n-1].text, line...)
+			return
+		}
+		chunks = append(chunks, codeChunk{text: slices.Clone(line), fenced: fenced})
+	}
+
+	var fence string
+	for _, line := range bytes.SplitAfter(src, []byte("\n")) {
+		trimmed := strings.TrimSpace(string(line))
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			add(line, true)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			add(line, true)
+			continue
+		}
+		add(line, false)
+	}
+
+	return chunks
+}
+
+// expandShortcodes replaces every shortcode in src, outside fenced code
+// blocks, with its output.
+func (inst *Instance) expandShortcodes(src []byte, sc *shortcodeContext) ([]byte, error) {
+	if !bytes.Contains(src, []byte("{{<")) {
+		return src, nil
+	}
+
+	var out bytes.Buffer
+	for _, chunk := range splitFencedCode(src) {
+		if chunk.fenced {
+			out.Write(chunk.text)
+			continue
+		}
+		for _, line := range bytes.SplitAfter(chunk.text, []byte("\n")) {
+			expanded, err := inst.expandShortcodeLine(line, sc)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(expanded)
+		}
+	}
+
+	return out.Bytes(), nil
+}
+
+func (inst *Instance) expandShortcodeLine(line []byte, sc *shortcodeContext) ([]byte, error) {
+	var expandErr error
+	expanded := shortcodeRe.ReplaceAllFunc(line, func(match []byte) []byte {
+		if expandErr != nil {
+			return match
+		}
+		m := shortcodeRe.FindSubmatch(match)
+		name := string(m[1])
+		fn, ok := shortcodes[name]
+		if !ok {
+			expandErr = fmt.Errorf("fsmarkdown: %s: unknown shortcode %q", sc.filePath, name)
+			return match
+		}
+		var args []string
+		for _, arg := range shortcodeArgRe.FindAllSubmatch(m[2], -1) {
+			args = append(args, string(arg[1]))
+		}
+		result, err := fn(inst, sc, args)
+		if err != nil {
+			expandErr = fmt.Errorf("%s: %s shortcode: %w", sc.filePath, name, err)
+			return match
+		}
+		return []byte(result)
+	})
+	return expanded, expandErr
+}
@@ -0,0 +1,74 @@
+package fsmarkdown
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sjc5/kit/pkg/response"
+)
+
+type xmlURLSet struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []xmlURL `xml:"url"`
+}
+
+type xmlURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// SitemapXML renders a sitemaps.org sitemap of every page, with absolute
+// URLs under baseURL. <lastmod> is the page's LastModified if known,
+// otherwise its frontmatter date.
+func (inst *Instance) SitemapXML(ctx context.Context, baseURL string) ([]byte, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	set := xmlURLSet{URLs: make([]xmlURL, 0, len(paths))}
+	for _, cleanPath := range paths {
+		p, found, err := inst.getListedPage(ctx, cleanPath)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		u := xmlURL{Loc: baseURL + p.URL}
+		if t, ok := parseDate(p.LastModified); ok {
+			u.LastMod = t.Format(time.RFC3339)
+		} else if t, ok := parseDate(p.Date); ok {
+			u.LastMod = t.Format("2006-01-02")
+		}
+		set.URLs = append(set.URLs, u)
+	}
+
+	out, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), out...), nil
+}
+
+// SitemapXMLHandler serves SitemapXML, e.g. at /sitemap.xml.
+func (inst *Instance) SitemapXMLHandler(baseURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out, err := inst.SitemapXML(r.Context(), baseURL)
+		if err != nil {
+			fmt.Println("Error building sitemap in SitemapXMLHandler: ", err)
+			response.New(w).InternalServerError()
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.Write(out)
+	})
+}
@@ -0,0 +1,69 @@
+package fsmarkdown
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/sjc5/kit/pkg/response"
+	"golang.org/x/sync/errgroup"
+)
+
+type Stats struct {
+	PageDetails CacheStats `json:"pageDetails"`
+	Sitemap     CacheStats `json:"sitemap"`
+	BasePage    CacheStats `json:"basePage"`
+}
+
+func (inst *Instance) Stats() Stats {
+	return Stats{
+		PageDetails: inst.pageDetailsCache.Stats(),
+		Sitemap:     inst.sitemapCache.Stats(),
+		BasePage:    inst.basePageCache.Stats(),
+	}
+}
+
+// StatsHandler serves Stats() as JSON, e.g. for a metrics endpoint.
+func (inst *Instance) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.New(w).JSON(inst.Stats())
+	})
+}
+
+// Prewarm parses every page in the markdown directory (and its sitemaps)
+// concurrently, up to Options.MaxConcurrency at once, so that the first
+// visitor to each page doesn't pay the parse cost. If the tree has more
+// pages than the caches hold, the least recently warmed pages are evicted.
+// Like listings, pages that fail to load are logged and skipped, unless
+// StrictFrontmatter is set.
+func (inst *Instance) Prewarm(ctx context.Context) error {
+	paths, err := inst.pagePaths()
+	if err != nil {
+		return err
+	}
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	eg.SetLimit(cap(inst.parseSem))
+
+	for _, cleanPath := range paths {
+		if egCtx.Err() != nil {
+			break
+		}
+		eg.Go(func() error {
+			if err := egCtx.Err(); err != nil {
+				return err
+			}
+			_, err := inst.getPageDetails(egCtx, cleanPath, 0)
+			if err == nil || inst.opts.StrictFrontmatter || egCtx.Err() != nil {
+				return err
+			}
+			fmt.Println("Skipping page that failed to prewarm: ", cleanPath, err)
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
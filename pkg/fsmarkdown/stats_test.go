@@ -0,0 +1,28 @@
+package fsmarkdown
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPrewarmSkipsBrokenPages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"markdown/_index.md": {Data: []byte("---\ntitle: Home\n---\nHello")},
+		"markdown/cyc.md":    {Data: []byte(`{{< include "cyc.md" >}}`)},
+		"markdown/ok.md":     {Data: []byte("---\ntitle: OK\n---\nFine")},
+	}
+
+	inst := New(fsys)
+	if err := inst.Prewarm(context.Background()); err != nil {
+		t.Fatalf("Prewarm() = %v, want nil", err)
+	}
+	if got := inst.Stats().BasePage.Size; got < 2 {
+		t.Errorf("base page cache size = %d, want the healthy pages cached", got)
+	}
+
+	strict := NewWithOptions(&Options{FS: fsys, StrictFrontmatter: true})
+	if err := strict.Prewarm(context.Background()); err == nil {
+		t.Error("Prewarm() with StrictFrontmatter = nil, want the include cycle error")
+	}
+}
@@ -0,0 +1,88 @@
+package fsmarkdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"text/template"
+
+	"github.com/adrg/frontmatter"
+)
+
+// TemplateData is the data passed to the body of a page with
+// "template: true" frontmatter, which is executed as a text/template after
+// shortcodes are expanded (so included fragments are templated too) and
+// before it is rendered, so it can produce markdown. Fenced code blocks are
+// left alone, though actions around them still repeat or drop them. Besides Options.TemplateFuncs, the body can call data
+// "file.key.path" to read data files (see Instance.Data). Missing map keys
+// are errors.
+type TemplateData struct {
+	// Page is the page being rendered, with its frontmatter fields and URL
+	// set (Content and TOC are not, yet).
+	Page *Page
+	// Params holds every frontmatter key, including ones Page has no field
+	// for.
+	Params map[string]any
+}
+
+// codeStub stands in for a fenced code block while a page's body is
+// executed as a template. codeStubRe matches it even if a trim marker
+// (e.g. "{{- end }}") ate its newline.
+const codeStub = "fsmarkdowncode%d"
+
+var codeStubRe = regexp.MustCompile(`fsmarkdowncode(\d+)\n?`)
+
+// executeTemplate runs a templated page's body (see TemplateData).
+func (inst *Instance) executeTemplate(body, fileBytes []byte, p *Page) ([]byte, error) {
+	var params map[string]any
+	if _, err := frontmatter.Parse(bytes.NewReader(fileBytes), &params); err != nil {
+		return nil, toFrontmatterParseError(p.FilePath, err)
+	}
+	params, _ = normalizeData(params).(map[string]any)
+
+	funcs := template.FuncMap{
+		"data": func(ref string) (any, error) {
+			v, err := inst.lookupData(ref)
+			if filePath, ok := inst.dataRefFile(ref); ok && err == nil {
+				p.DependsOn = append(p.DependsOn, filePath)
+			}
+			return v, err
+		},
+	}
+	for name, fn := range inst.opts.TemplateFuncs {
+		funcs[name] = fn
+	}
+
+	// Fenced code is swapped for placeholders while the template runs
+	var src bytes.Buffer
+	var fenced [][]byte
+	for _, chunk := range splitFencedCode(body) {
+		if chunk.fenced {
+			fmt.Fprintf(&src, codeStub+"\n", len(fenced))
+			fenced = append(fenced, chunk.text)
+			continue
+		}
+		src.Write(chunk.text)
+	}
+
+	tmpl, err := template.New(p.FilePath).Option("missingkey=error").Funcs(funcs).Parse(src.String())
+	if err != nil {
+		return nil, fmt.Errorf("fsmarkdown: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, &TemplateData{Page: p, Params: params}); err != nil {
+		return nil, fmt.Errorf("fsmarkdown: %w", err)
+	}
+
+	// Every copy of a stub is restored (a block in a {{range}} is output
+	// once per iteration); stubs in branches not taken are simply absent
+	return codeStubRe.ReplaceAllFunc(buf.Bytes(), func(m []byte) []byte {
+		i, err := strconv.Atoi(string(codeStubRe.FindSubmatch(m)[1]))
+		if err != nil || i >= len(fenced) {
+			return m
+		}
+		return fenced[i]
+	}), nil
+}
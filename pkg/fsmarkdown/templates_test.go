@@ -0,0 +1,91 @@
+package fsmarkdown
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExecuteTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		want     []string
+		wantNot  []string
+		wantErr  bool
+		wantCode int // number of <pre> blocks
+	}{
+		{
+			name: "fields",
+			page: "---\ntitle: Hi\ntemplate: true\ncolor: red\n---\n{{ .Page.Title }} is {{ .Params.color }}",
+			want: []string{"Hi is red"},
+		},
+		{
+			name:    "off by default",
+			page:    "---\ntitle: Hi\n---\n{{ .Page.Title }}",
+			want:    []string{"{{ .Page.Title }}"},
+			wantNot: []string{"<p>Hi</p>"},
+		},
+		{
+			name:     "fenced code is left alone",
+			page:     "---\ntemplate: true\n---\n```\n{{ .Page.Title }}\n```\n",
+			want:     []string{"{{ .Page.Title }}"},
+			wantCode: 1,
+		},
+		{
+			name:     "fenced code in range",
+			page:     "---\ntemplate: true\nitems: [a, b, c]\n---\n{{ range .Params.items }}\n```\ncode\n```\n{{ end }}\n",
+			wantNot:  []string{"fsmarkdowncode"},
+			wantCode: 3,
+		},
+		{
+			name:     "fenced code in trimmed range",
+			page:     "---\ntemplate: true\nitems: [a, b]\n---\n{{- range .Params.items }}\n```\ncode\n```\n{{- end }}\n",
+			wantNot:  []string{"fsmarkdowncode"},
+			wantCode: 2,
+		},
+		{
+			name:    "fenced code in false if",
+			page:    "---\ntemplate: true\n---\n{{ if false }}\n```\ncode\n```\n{{ end }}\nafter",
+			want:    []string{"after"},
+			wantNot: []string{"fsmarkdowncode", "<pre>"},
+		},
+		{
+			name:    "missing key",
+			page:    "---\ntemplate: true\n---\n{{ .Params.nope }}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := New(fstest.MapFS{"markdown/page.md": {Data: []byte(tt.page)}})
+			p, _, err := inst.getPageBase(context.Background(), "/page")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("getPageBase() = nil error, want one")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			content := string(p.Content)
+			for _, s := range tt.want {
+				if !strings.Contains(content, s) {
+					t.Errorf("content %q does not contain %q", content, s)
+				}
+			}
+			for _, s := range tt.wantNot {
+				if strings.Contains(content, s) {
+					t.Errorf("content %q contains %q", content, s)
+				}
+			}
+			if got := strings.Count(content, "<pre>"); tt.wantCode > 0 && got != tt.wantCode {
+				t.Errorf("content has %d code blocks, want %d: %q", got, tt.wantCode, content)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package fsmarkdown
+
+import (
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// pagePaths returns the clean URL path of every page in the markdown
+// directory (or the loaded bundle), sorted. A "foo.md", a "foo/_index.md"
+// and a page bundle's "foo/index.md" all map to "/foo" and are reported
+// once.
+func (inst *Instance) pagePaths() ([]string, error) {
+	if inst.bundle != nil {
+		paths := make([]string, 0, len(inst.bundle.Pages))
+		for p := range inst.bundle.Pages {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		return paths, nil
+	}
+
+	seen := map[string]bool{}
+
+	err := fs.WalkDir(inst.FS, "markdown", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".md") || isPartial(p) {
+			return nil
+		}
+
+		sourcePath, _ := inst.sourcePathForFile(p)
+		seen[sourcePath] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(seen))
+	for p := range seen {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
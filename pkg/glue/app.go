@@ -13,6 +13,11 @@ import (
 
 func (fw *Instance[AHD, SE, CEE]) App() {
 	port := fw.GetEnv().Meta.Port
+
+	if fw.Markdown != nil && fw.GetEnv().Meta.IsProd {
+		fw.Markdown.MustValidate()
+	}
+
 	r := fw.initRouter()
 
 	server := &http.Server{
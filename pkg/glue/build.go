@@ -1,7 +1,12 @@
 package glue
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+
 	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/sjc5/_lotus/pkg/fsmarkdown"
 	"github.com/sjc5/hwy"
 	"github.com/sjc5/kit/pkg/fsutil"
 )
@@ -9,6 +14,20 @@ import (
 func (fw *Instance[AHD, SE, CEE]) Build() {
 	fw.mustMultiBuild()
 
+	// Images first: their manifest is used when rendering the bundle, and
+	// the variants must exist before kiruna hashes the public dir.
+	if err := fw.buildMarkdownImages(); err != nil {
+		panic(err)
+	}
+
+	if err := fw.buildMarkdownBundle(); err != nil {
+		panic(err)
+	}
+
+	if err := fw.buildMarkdownSearchIndex(); err != nil {
+		panic(err)
+	}
+
 	if err := fw.Kiruna.Build(); err != nil {
 		panic(err)
 	}
@@ -67,3 +86,65 @@ func (fw *Instance[AHD, SE, CEE]) vendorFiles() error {
 
 	return nil
 }
+
+func (fw *Instance[AHD, SE, CEE]) buildMarkdownImages() error {
+	if fw.Markdown == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := fw.Markdown.WithFS(os.DirFS(privateStaticDir)).WriteImageVariants(publicStaticDir, &buf); err != nil {
+		return err
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	return os.WriteFile(filepath.Join(privateStaticDir, fsmarkdown.ImageManifestFileName), buf.Bytes(), 0644)
+}
+
+func (fw *Instance[AHD, SE, CEE]) buildMarkdownBundle() error {
+	if fw.Markdown == nil {
+		return nil
+	}
+
+	md := fw.Markdown.WithFS(os.DirFS(privateStaticDir))
+	if fw.MarkdownGitHistory {
+		history, err := fsmarkdown.NewGitHistory(privateStaticDir)
+		if err != nil {
+			return err
+		}
+		md = md.WithHistory(history)
+	}
+
+	var buf bytes.Buffer
+	if err := md.WriteBundle(&buf); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(privateStaticDir, fsmarkdown.BundleFileName), buf.Bytes(), 0644)
+}
+
+// The search index goes in the public dir so kiruna hashes it; the frontend
+// looks it up by FSMARKDOWN_SEARCH_INDEX_FILE.
+func (fw *Instance[AHD, SE, CEE]) buildMarkdownSearchIndex() error {
+	if fw.Markdown == nil || !fw.MarkdownSearchIndex {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := fw.Markdown.WithFS(os.DirFS(privateStaticDir)).WriteSearchIndex(&buf); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(publicStaticDir, fsmarkdown.SearchIndexFileName), buf.Bytes(), 0644)
+}
+
+// A bundle left over from a previous build would shadow edits made in dev.
+func (fw *Instance[AHD, SE, CEE]) removeMarkdownBundle() error {
+	err := os.Remove(filepath.Join(privateStaticDir, fsmarkdown.BundleFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package glue
 
 import (
+	"github.com/sjc5/_lotus/pkg/fsmarkdown"
 	"github.com/sjc5/hwy"
 	"github.com/sjc5/kiruna"
 )
@@ -10,6 +11,10 @@ func (fw *Instance[AHD, SE, CEE]) Dev() {
 		panic(err)
 	}
 
+	if err := fw.removeMarkdownBundle(); err != nil {
+		panic(err)
+	}
+
 	fw.mustMultiBuild()
 
 	fw.Kiruna.MustStartDev(&kiruna.DevConfig{
@@ -18,6 +23,7 @@ func (fw *Instance[AHD, SE, CEE]) Dev() {
 			Dirs: []string{
 				"__*",
 				"static/public/prehashed",
+				publicStaticDir + "/" + fsmarkdown.ImageVariantsDir,
 			},
 			Files: []string{
 				"**/*.d.ts",
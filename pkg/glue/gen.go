@@ -1,6 +1,7 @@
 package glue
 
 import (
+	"github.com/sjc5/_lotus/pkg/fsmarkdown"
 	"github.com/sjc5/hwy"
 	"github.com/sjc5/kit/pkg/executil"
 	"github.com/sjc5/kit/pkg/tsgen"
@@ -45,6 +46,10 @@ func (fw *Instance[AHD, SE, CEE]) getExtraTSCode() string {
 	a.Serialize("const PUBLIC_FILE_MAP_KEYS", keys)
 	a.Raw("export type PublicFileMapKey", "typeof PUBLIC_FILE_MAP_KEYS[number]")
 
+	if fw.Markdown != nil && fw.MarkdownSearchIndex {
+		fsmarkdown.AddSearchIndexTS(&a)
+	}
+
 	if fw.GenerateExtraTSCode != nil {
 		fw.GenerateExtraTSCode(&a)
 	}
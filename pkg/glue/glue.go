@@ -1,10 +1,12 @@
 package glue
 
 import (
+	"fmt"
 	"io/fs"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/sjc5/_lotus/pkg/fsmarkdown"
 	"github.com/sjc5/hwy"
 	"github.com/sjc5/kiruna"
 )
@@ -22,6 +24,7 @@ type (
 	FilesToVendor        = [][2]string
 	RobotsTxt            = string
 	IsOpenGraphImage     func(urlPath string) bool
+	IsMarkdownRoute      func(urlPath string) bool
 	RootID               = string
 	DistFS               fs.FS
 	GetDefaultHeadBlocks func(r *http.Request) ([]HeadBlock, error)
@@ -38,6 +41,13 @@ type InstanceOptions[AHD any, SE any, CEE any] struct {
 	GetDefaultHeadBlocks   GetDefaultHeadBlocks
 	RootID                 RootID
 	Kiruna                 *kiruna.Kiruna
+	Markdown               *fsmarkdown.Instance
+	MarkdownSEO            *fsmarkdown.SEOOptions
+	MarkdownRoute          IsMarkdownRoute
+	MarkdownGitHistory     bool
+	MarkdownSearchIndex    bool
+	MarkdownRaw            *fsmarkdown.RawMarkdownOptions
+	MarkdownLLMs           *fsmarkdown.LLMsOptions
 	GeneralMiddlewares     Middlewares
 	ModifyRouter           func(r *chi.Mux)
 	GetEnv                 GetEnv[SE, CEE]
@@ -59,5 +69,17 @@ func New[AHD any, SE any, CEE any](opts *InstanceOptions[AHD, SE, CEE]) *Instanc
 		instance.GetEnv = MakeGetEnv[SE, CEE]()
 	}
 
+	if instance.MarkdownSEO != nil && instance.MarkdownRoute == nil {
+		fmt.Println("MarkdownSEO is set without MarkdownRoute, so no markdown head blocks will be added")
+	}
+
+	// Responsive image URLs go through kiruna, so they are cache-busted and
+	// served under /public/
+	if instance.Markdown != nil {
+		instance.Markdown.SetDefaultImageURL(func(publicPath string) string {
+			return instance.Kiruna.GetPublicURL(publicPath)
+		})
+	}
+
 	return instance
 }
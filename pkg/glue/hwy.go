@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/sjc5/_lotus/pkg/fsmarkdown"
 	"github.com/sjc5/hwy"
 	"github.com/sjc5/kit/pkg/theme"
 )
@@ -23,7 +24,7 @@ func (fw *Instance[AHD, SE, CEE]) getHwy() *hwy.Hwy {
 		Loaders:              fw.DataFuncs.Loaders,
 		QueryActions:         fw.DataFuncs.QueryActions,
 		MutationActions:      fw.DataFuncs.MutationActions,
-		GetDefaultHeadBlocks: fw.GetDefaultHeadBlocks,
+		GetDefaultHeadBlocks: fw.getDefaultHeadBlocks,
 		GetRootTemplateData: func(r *http.Request) (map[string]any, error) {
 			return map[string]any{
 				"Kiruna":                      fw.Kiruna,
@@ -49,3 +50,39 @@ func (fw *Instance[AHD, SE, CEE]) getHwy() *hwy.Hwy {
 
 	return &hwyInstance
 }
+
+// getDefaultHeadBlocks appends head blocks for markdown pages (if
+// MarkdownSEO is set) after the user's defaults, so the page's title and
+// description take precedence over the defaults. Only routes matching
+// MarkdownRoute are looked up, so MarkdownSEO does nothing without it. A
+// page that fails to load is logged and gets the defaults only, rather than
+// failing the request.
+func (fw *Instance[AHD, SE, CEE]) getDefaultHeadBlocks(r *http.Request) ([]HeadBlock, error) {
+	var blocks []HeadBlock
+
+	if fw.GetDefaultHeadBlocks != nil {
+		defaultBlocks, err := fw.GetDefaultHeadBlocks(r)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, defaultBlocks...)
+	}
+
+	if fw.Markdown == nil || fw.MarkdownSEO == nil {
+		return blocks, nil
+	}
+	if fw.MarkdownRoute == nil || !fw.MarkdownRoute(r.URL.Path) {
+		return blocks, nil
+	}
+
+	dp, err := fw.Markdown.GetPageDetails(r)
+	if err != nil {
+		fmt.Println("Error getting markdown page for head blocks: ", err)
+		return blocks, nil
+	}
+	if dp != nil && !dp.IsNotFound() {
+		blocks = append(blocks, fsmarkdown.HeadBlocks(dp, fw.MarkdownSEO)...)
+	}
+
+	return blocks, nil
+}
@@ -8,13 +8,18 @@ import (
 
 type Kiruna = kiruna.Kiruna
 
+const (
+	privateStaticDir = "static/private"
+	publicStaticDir  = "static/public"
+)
+
 func NewKiruna(distFS fs.FS) *kiruna.Kiruna {
 	return kiruna.New(&kiruna.Config{
 		DistFS:           distFS,
 		MainAppEntry:     "cmd/app/main.go",
 		DistDir:          "dist",
-		PrivateStaticDir: "static/private",
-		PublicStaticDir:  "static/public",
+		PrivateStaticDir: privateStaticDir,
+		PublicStaticDir:  publicStaticDir,
 		StylesDir:        "styles",
 	})
 }
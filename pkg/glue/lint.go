@@ -0,0 +1,34 @@
+package glue
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sjc5/_lotus/pkg/fsmarkdown"
+)
+
+func (fw *Instance[AHD, SE, CEE]) LintContent(asJSON bool) {
+	if fw.Markdown == nil {
+		panic("LintContent requires InstanceOptions.Markdown to be set")
+	}
+
+	// Lint the source files on disk, not the (possibly stale) built copy
+	report, err := fw.Markdown.WithFS(os.DirFS(privateStaticDir)).Lint(&fsmarkdown.LintOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	if asJSON {
+		b, err := report.JSON()
+		if err != nil {
+			panic(err)
+		}
+		fmt.Println(string(b))
+	} else {
+		fmt.Print(report.Text())
+	}
+
+	if report.HasErrors() {
+		os.Exit(1)
+	}
+}
@@ -32,6 +32,15 @@ func (fw *Instance[AHD, SE, CEE]) initRouter() *chi.Mux {
 		response.New(w).Text(fw.RobotsTxt)
 	})
 
+	if fw.Markdown != nil && fw.MarkdownSEO != nil && fw.MarkdownSEO.BaseURL != "" {
+		r.Handle("/sitemap.xml", fw.Markdown.SitemapXMLHandler(fw.MarkdownSEO.BaseURL))
+	}
+
+	if fw.Markdown != nil && fw.MarkdownLLMs != nil {
+		r.Handle("/llms.txt", fw.Markdown.LLMsTxtHandler(fw.MarkdownLLMs))
+		r.Handle("/llms-full.txt", fw.Markdown.LLMsFullTxtHandler(fw.MarkdownLLMs))
+	}
+
 	r.Handle("/public/*", fw.Kiruna.MustGetServeStaticHandler("/public/", true))
 
 	r.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
@@ -44,6 +53,13 @@ func (fw *Instance[AHD, SE, CEE]) initRouter() *chi.Mux {
 	})
 
 	r.Group(func(r chi.Router) {
+		if fw.Markdown != nil {
+			r.Use(fw.Markdown.RedirectMiddleware)
+			r.Use(fw.Markdown.BundleAssetsMiddleware)
+		}
+		if fw.Markdown != nil && fw.MarkdownRaw != nil {
+			r.Use(fw.Markdown.RawMarkdownMiddleware(fw.MarkdownRaw))
+		}
 		r.Use(fw.adHocDataMiddleware)
 		r.Handle("/*", fw.getHwy().GetRootHandler())
 	})
@@ -6,6 +6,8 @@ func (fw *Instance[AHD, SE, CEE]) Tasks() {
 	devFlag := flag.Bool("dev", false, "Run Dev function")
 	buildFlag := flag.Bool("build", false, "Run Build function")
 	genFlag := flag.Bool("gen", false, "Run Gen function")
+	lintContentFlag := flag.Bool("lint-content", false, "Run LintContent function")
+	jsonFlag := flag.Bool("json", false, "Output -lint-content results as JSON")
 
 	flag.Parse()
 
@@ -20,10 +22,13 @@ func (fw *Instance[AHD, SE, CEE]) Tasks() {
 	if *genFlag {
 		flagCount++
 	}
+	if *lintContentFlag {
+		flagCount++
+	}
 
 	// Panic if no flags or multiple flags are set
 	if flagCount == 0 {
-		panic("No command flag specified. Use one of: -main, -dev, -build, -gen")
+		panic("No command flag specified. Use one of: -main, -dev, -build, -gen, -lint-content")
 	}
 	if flagCount > 1 {
 		panic("Only one command flag can be specified at a time")
@@ -37,5 +42,7 @@ func (fw *Instance[AHD, SE, CEE]) Tasks() {
 		fw.Build()
 	case *genFlag:
 		fw.Gen()
+	case *lintContentFlag:
+		fw.LintContent(*jsonFlag)
 	}
 }